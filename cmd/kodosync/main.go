@@ -0,0 +1,99 @@
+// Command kodosync 把本地目录同步到七牛存储空间的前缀下
+//
+// 用法:
+//
+//	kodosync [flags] <local-dir> <bucket>[/<prefix>]
+//
+// 访问密钥从环境变量QINIU_ACCESS_KEY, QINIU_SECRET_KEY或者共享配置文件中读取
+//
+//	kodosync -delete -exclude '*.tmp' ./public my-bucket/www/
+//	kodosync -dry-run -include 'css/*' ./public my-bucket/www/
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/session"
+	"github.com/QN-zhangzhuo/go-sdk/service/kodo"
+)
+
+// patterns 可以重复指定的flag
+type patterns []string
+
+func (p *patterns) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *patterns) Set(v string) error {
+	*p = append(*p, v)
+	return nil
+}
+
+func main() {
+	var (
+		input       kodo.SyncInput
+		concurrency int
+		verbose     bool
+	)
+	flag.BoolVar(&input.Delete, "delete", false, "delete objects that have no corresponding local file")
+	flag.BoolVar(&input.DryRun, "dry-run", false, "only print the actions, do not upload or delete")
+	flag.BoolVar(&input.CheckHash, "check-hash", false, "always compare qetag for files of the same size, ignoring mtime")
+	flag.StringVar(&input.Region, "region", "", "region of the bucket, e.g. z0")
+	flag.IntVar(&concurrency, "concurrency", kodo.DefaultSyncConcurrency, "number of files uploaded concurrently")
+	flag.BoolVar(&verbose, "v", false, "print every action")
+	flag.Var((*patterns)(&input.Include), "include", "only sync files matching the glob, can be repeated")
+	flag.Var((*patterns)(&input.Exclude), "exclude", "do not sync files matching the glob, can be repeated")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] <local-dir> <bucket>[/<prefix>]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	input.LocalDir = flag.Arg(0)
+	input.Bucket = flag.Arg(1)
+	if i := strings.Index(input.Bucket, "/"); i >= 0 {
+		input.Bucket, input.Prefix = input.Bucket[:i], input.Bucket[i+1:]
+	}
+
+	cfg := &qiniu.Config{}
+	if input.Region != "" {
+		cfg.Region = qiniu.String(input.Region)
+	}
+	sess, err := session.New(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "kodosync:", err)
+		os.Exit(1)
+	}
+	syncer := kodo.NewSyncer(kodo.NewService(sess), func(s *kodo.Syncer) {
+		s.Concurrency = concurrency
+		s.Uploader.Recorder = nil
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	out, err := syncer.SyncWithContext(ctx, &input)
+	if out != nil {
+		for _, a := range out.Actions {
+			if a.Error != nil {
+				fmt.Fprintf(os.Stderr, "%s %s failed: %v\n", a.Op, a.Key, a.Error)
+			} else if verbose || input.DryRun {
+				fmt.Printf("%s %s (%s)\n", a.Op, a.Key, a.Reason)
+			}
+		}
+		fmt.Println(out.Summary())
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "kodosync:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,136 @@
+package encoding
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Decoder decodes values from a map[string][]string to a struct.
+//
+// Keys that don't match any field are ignored. Slices of structs are not
+// supported and are ignored as well.
+type Decoder struct {
+	cache *cache
+}
+
+// NewDecoder returns a new Decoder.
+func NewDecoder() *Decoder {
+	return &Decoder{cache: newCache()}
+}
+
+// SetAliasTag changes the tag used to locate custom field aliases.
+// The default tag is "schema".
+func (d *Decoder) SetAliasTag(tag string) {
+	d.cache.tag = tag
+}
+
+// RegisterConverter registers a converter function for a custom type.
+func (d *Decoder) RegisterConverter(value interface{}, converterFunc Converter) {
+	d.cache.registerConverter(value, converterFunc)
+}
+
+// Decode decodes a map[string][]string to a struct.
+//
+// The first parameter must be a pointer to a struct.
+//
+// The second parameter is a map, typically url.Values from an HTTP request.
+// Keys are "paths" in dotted notation to the struct fields.
+func (d *Decoder) Decode(dst interface{}, src map[string][]string) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("schema: interface must be a pointer to struct")
+	}
+	v = v.Elem()
+	t := v.Type()
+	errors := MultiError{}
+	for path, values := range src {
+		if len(values) == 0 {
+			continue
+		}
+		parts, err := d.cache.parsePath(path, t)
+		if err != nil || len(parts) != 1 {
+			continue
+		}
+		if err := d.decode(v, parts[0], values); err != nil {
+			errors[path] = err
+		}
+	}
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
+// decode fills a struct field using a parsed path.
+func (d *Decoder) decode(v reflect.Value, part pathPart, values []string) error {
+	for _, name := range part.path {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.FieldByName(name)
+	}
+	if !v.CanSet() {
+		return nil
+	}
+
+	t := v.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		if v.IsNil() {
+			v.Set(reflect.New(t))
+		}
+		v = v.Elem()
+	}
+
+	if t.Kind() == reflect.Slice {
+		conv := d.converter(t.Elem())
+		if conv == nil {
+			return fmt.Errorf("schema: converter not found for %v", t.Elem())
+		}
+		items := reflect.MakeSlice(t, 0, len(values))
+		for _, value := range values {
+			item := conv(value)
+			if !item.IsValid() {
+				return ConversionError{Type: t.Elem(), Value: value}
+			}
+			items = reflect.Append(items, item.Convert(t.Elem()))
+		}
+		v.Set(items)
+		return nil
+	}
+
+	conv := d.converter(t)
+	if conv == nil {
+		return fmt.Errorf("schema: converter not found for %v", t)
+	}
+	// Use the last value, like url.Values.Set would.
+	value := values[len(values)-1]
+	item := conv(value)
+	if !item.IsValid() {
+		return ConversionError{Type: t, Value: value}
+	}
+	v.Set(item.Convert(t))
+	return nil
+}
+
+// converter returns the converter for a type, registered converters first.
+func (d *Decoder) converter(t reflect.Type) Converter {
+	if conv := d.cache.converter(t); conv != nil {
+		return conv
+	}
+	return builtinConverters[t.Kind()]
+}
+
+// ConversionError stores information about a failed conversion.
+type ConversionError struct {
+	Type  reflect.Type // expected type of elem
+	Value string       // value that failed to convert
+}
+
+func (e ConversionError) Error() string {
+	return fmt.Sprintf("schema: error converting value %q to %v", e.Value, e.Type)
+}
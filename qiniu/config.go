@@ -22,18 +22,19 @@
 // QINIU_API_HOST = ""
 //
 // [z0]
-// UpHosts = "domain1,domain2"
-// CdnUpHosts = "domain1,domain2"
-// RsHost = "",
-// RsfHost = "",
-// ApiHost = "",
-// IoHost = "",
+// qiniu_up_hosts = domain1,domain2
+// qiniu_acc_up_hosts = domain1,domain2
+// qiniu_rs_host = ""
+// qiniu_rsf_host = ""
+// qiniu_api_host = ""
+// qiniu_io_host = ""
 package qiniu
 
 import (
 	"net/http"
 
 	"github.com/QN-zhangzhuo/go-sdk/qiniu/credentials"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/defs"
 )
 
 // RequestRetryer 是request.Retryer别名
@@ -109,6 +110,9 @@ type Config struct {
 
 	UcHost *string
 
+	// FusionHost CDN服务的域名， 默认为fusion.qiniuapi.com
+	FusionHost *string
+
 	// 存储空间所在的区域的名字
 	// 支持的区域名字：
 	// [`z0`, `z1`, `z2`, `na0`, `as0`]
@@ -118,8 +122,21 @@ type Config struct {
 	// 如果后续的接口使用的都是一个区域的存储空间，可以设置该值。
 	// 比如要操作或者请求服务的存储空间属于不同的存储空间，可以
 	// 在具体的接口输入中设置region值，可以覆盖这个地方的配置
+	//
+	// 接口输入和这里都没有设置的时候， 会通过UcHost查询存储空间所在的区域， 参考DisableRegionQuery
 	Region *string
 
+	// RegionHosts 各个存储区域的域名配置， key为区域的名字
+	// 没有配置的区域或者字段使用defs包中该区域的默认域名
+	RegionHosts map[string]*defs.Host
+
+	// 禁用存储区域的自动查询
+	// 接口输入和Config都没有设置Region的时候， 默认会通过UcHost查询存储空间所在的区域
+	DisableRegionQuery *bool
+
+	// 存储区域查询结果的磁盘缓存文件， 为空的时候只在内存中缓存
+	RegionCacheFile *string
+
 	// UploadConcurrency 分片上传的goroutine最大并发上传数量
 	// 如果该字段的值<=0或者为nil, 那么使用默认的DefaultUploadConcurrency
 	UploadConcurrency *int
@@ -136,6 +153,7 @@ type Config struct {
 	DisableRecorder *bool
 
 	// 分片上传的块数达到StoreNumber就保存上传记录信息到本地文件，以实现断点续传
+	// 上传记录的保存方式可以通过kodo.Uploader.RecordStore定制
 	// 如果设置的值小于等于0， 该字段将被忽略，将使用默认的DefaultStoreNumber值
 	StoreNumber *int
 
@@ -183,6 +201,12 @@ func (c *Config) WithGaeaHost(h string) *Config {
 	return c
 }
 
+// WithProgressRecorder 设置分片上传的进度实现
+func (c *Config) WithProgressRecorder(r Recorder) *Config {
+	c.ProgressRecorder = r
+	return c
+}
+
 // WithDisableRecorder 设置DisableRecorder
 func (c *Config) WithDisableRecorder(disable bool) *Config {
 	c.DisableRecorder = &disable
@@ -225,6 +249,33 @@ func (c *Config) WithPass(p string) *Config {
 	return c
 }
 
+// WithRegion 设置存储空间所在的区域
+func (c *Config) WithRegion(region string) *Config {
+	c.Region = &region
+	return c
+}
+
+// WithRegionHost 设置存储区域region的域名配置
+func (c *Config) WithRegionHost(region string, host *defs.Host) *Config {
+	if c.RegionHosts == nil {
+		c.RegionHosts = make(map[string]*defs.Host)
+	}
+	c.RegionHosts[region] = host
+	return c
+}
+
+// WithDisableRegionQuery 开启或者关闭存储区域的自动查询
+func (c *Config) WithDisableRegionQuery(disable bool) *Config {
+	c.DisableRegionQuery = &disable
+	return c
+}
+
+// WithRegionCacheFile 设置存储区域查询结果的磁盘缓存文件
+func (c *Config) WithRegionCacheFile(path string) *Config {
+	c.RegionCacheFile = &path
+	return c
+}
+
 // WithUploadConcurrency 设置分片上传的最大并发上传可以开启的goroutine数量
 func (c *Config) WithUploadConcurrency(concurrency int) *Config {
 	c.UploadConcurrency = &concurrency
@@ -291,6 +342,12 @@ func (c *Config) WithUCHost(host string) *Config {
 	return c
 }
 
+// WithFusionHost 设置Config.FusionHost字段
+func (c *Config) WithFusionHost(host string) *Config {
+	c.FusionHost = &host
+	return c
+}
+
 // MergeIn 合并传入的cfs信息到c中
 func (c *Config) MergeIn(cfgs ...*Config) {
 	for _, other := range cfgs {
@@ -332,6 +389,9 @@ func mergeInConfig(dst *Config, other *Config) {
 	if other.HTTPClient != nil {
 		dst.HTTPClient = other.HTTPClient
 	}
+	if other.ProgressRecorder != nil {
+		dst.ProgressRecorder = other.ProgressRecorder
+	}
 	if other.DisableRecorder != nil {
 		dst.DisableRecorder = other.DisableRecorder
 	}
@@ -365,6 +425,21 @@ func mergeInConfig(dst *Config, other *Config) {
 	if other.APIHost != nil {
 		dst.APIHost = other.APIHost
 	}
+	if other.FusionHost != nil {
+		dst.FusionHost = other.FusionHost
+	}
+	if other.Region != nil {
+		dst.Region = other.Region
+	}
+	if other.RegionHosts != nil {
+		dst.RegionHosts = other.RegionHosts
+	}
+	if other.DisableRegionQuery != nil {
+		dst.DisableRegionQuery = other.DisableRegionQuery
+	}
+	if other.RegionCacheFile != nil {
+		dst.RegionCacheFile = other.RegionCacheFile
+	}
 	if other.UploadConcurrency != nil {
 		dst.UploadConcurrency = other.UploadConcurrency
 	}
@@ -180,11 +180,13 @@ var ValidateResponseHandler = request.NamedHandler{Name: "core.ValidateResponseH
 		if length > 0 {
 			if t := r.HTTPResponse.Header.Get("Content-Type"); t == "application/json" {
 				var em ErrMsg
-				var bf bytes.Buffer
 
-				defer r.HTTPResponse.Body.Close()
-
-				err := json.NewDecoder(io.TeeReader(r.HTTPResponse.Body, &bf)).Decode(&em)
+				// 读取完整的响应体， 后面的handler(比如批量操作)还需要使用
+				body, err := ioutil.ReadAll(r.HTTPResponse.Body)
+				r.HTTPResponse.Body.Close()
+				if err == nil {
+					err = json.Unmarshal(body, &em)
+				}
 				if err != nil {
 					r.Error = qerr.New(qerr.ErrCodeDeserialization, "decode json data error", err)
 				}
@@ -192,7 +194,7 @@ var ValidateResponseHandler = request.NamedHandler{Name: "core.ValidateResponseH
 					errMsg = em.Err
 				}
 
-				r.HTTPResponse.Body = ioutil.NopCloser(bytes.NewReader(bf.Bytes()))
+				r.HTTPResponse.Body = ioutil.NopCloser(bytes.NewReader(body))
 			}
 		}
 		if errMsg != "" {
@@ -200,48 +202,63 @@ var ValidateResponseHandler = request.NamedHandler{Name: "core.ValidateResponseH
 		} else {
 			errMsg = r.HTTPResponse.Status
 		}
-		// this may be replaced by an UnmarshalError handler
-		switch r.HTTPResponse.StatusCode {
-		case 298:
-			r.Error = qerr.New(qerr.ErrPartFailed, errMsg, nil)
-		case 400:
-			r.Error = qerr.New(qerr.ErrParams, errMsg, nil)
-		case 401:
-			r.Error = qerr.New(qerr.ErrAuthorization, errMsg, nil)
-		case 403:
-			r.Error = qerr.New(qerr.ErrAccessForbidden, errMsg, nil)
-		case 404:
-			r.Error = qerr.New(qerr.ErrNotFound, errMsg, nil)
-		case 405:
-			r.Error = qerr.New(qerr.ErrUnexpectedRequest, errMsg, nil)
-		case 406:
-			r.Error = qerr.New(qerr.ErrCrc32Verification, errMsg, nil)
-		case 419:
-			r.Error = qerr.New(qerr.ErrAccountFrozen, errMsg, nil)
-		case 478:
-			r.Error = qerr.New(qerr.ErrMirrorSourceRequest, errMsg, nil)
-		case 503:
-			r.Error = qerr.New(qerr.ErrServiceUnavailable, errMsg, nil)
-		case 504:
-			r.Error = qerr.New(qerr.ErrServiceTimeout, errMsg, nil)
-		case 573:
-			r.Error = qerr.New(qerr.ErrRequestRate, errMsg, nil)
-		case 579:
-			r.Error = qerr.New(qerr.ErrUploadCallback, errMsg, nil)
-		case 599:
-			r.Error = qerr.New(qerr.ErrServiceOps, errMsg, nil)
-		case 608:
-			r.Error = qerr.New(qerr.ErrContentChanged, errMsg, nil)
-		case 612:
-			r.Error = qerr.New(qerr.ErrResourceNotExist, errMsg, nil)
-		case 614:
-			r.Error = qerr.New(qerr.ErrResourceExist, errMsg, nil)
-		default:
-			r.Error = qerr.New(qerr.ErrUnknown, errMsg, nil)
-		}
+		r.Error = qerr.New(ErrorCode(r.HTTPResponse.StatusCode), errMsg, nil)
 	}
 }}
 
+// ErrorCode 返回http 状态码对应的qerr错误码
+// 批量操作中每个操作的状态码也使用同样的对应关系
+func ErrorCode(statusCode int) string {
+	switch statusCode {
+	case 298:
+		return qerr.ErrPartFailed
+	case 400:
+		return qerr.ErrParams
+	case 401:
+		return qerr.ErrAuthorization
+	case 403:
+		return qerr.ErrAccessForbidden
+	case 404:
+		return qerr.ErrNotFound
+	case 405:
+		return qerr.ErrUnexpectedRequest
+	case 406:
+		return qerr.ErrCrc32Verification
+	case 416:
+		return qerr.ErrRangeNotSatisfiable
+	case 419:
+		return qerr.ErrAccountFrozen
+	case 478:
+		return qerr.ErrMirrorSourceRequest
+	case 503:
+		return qerr.ErrServiceUnavailable
+	case 504:
+		return qerr.ErrServiceTimeout
+	case 573:
+		return qerr.ErrRequestRate
+	case 579:
+		return qerr.ErrUploadCallback
+	case 599:
+		return qerr.ErrServiceOps
+	case 608:
+		return qerr.ErrContentChanged
+	case 612:
+		return qerr.ErrResourceNotExist
+	case 614:
+		return qerr.ErrResourceExist
+	case 630:
+		return qerr.ErrStorageLimit
+	case 631:
+		return qerr.ErrStorageNotExist
+	case 640:
+		return qerr.ErrInvalidMarker
+	case 701:
+		return qerr.ErrInvalidCtx
+	default:
+		return qerr.ErrUnknown
+	}
+}
+
 // AfterRetryHandler 决定请求是否重试，重试的间隔多长
 var AfterRetryHandler = request.NamedHandler{Name: "core.AfterRetryHandler", Fn: func(r *request.Request) {
 	if r.Retryable == nil || qiniu.BoolValue(r.Config.EnforceShouldRetryCheck) {
@@ -21,7 +21,7 @@ var QboxTokenRequestHandler = request.NamedHandler{
 			r.Error = qerr.New(credentials.ErrSignRequest, "sign request error", err)
 			return
 		}
-		r.HTTPRequest.Header.Add("Authorization", "QBox "+token)
+		r.HTTPRequest.Header.Set("Authorization", "QBox "+token)
 	},
 }
 
@@ -40,6 +40,6 @@ var QiniuTokenRequestHandler = request.NamedHandler{
 			r.Error = qerr.New(credentials.ErrSignRequest, "sign request error", err)
 			return
 		}
-		r.HTTPRequest.Header.Add("Authorization", "Qiniu "+token)
+		r.HTTPRequest.Header.Set("Authorization", "Qiniu "+token)
 	},
 }
@@ -22,6 +22,8 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/QN-zhangzhuo/go-sdk/qiniu/defs"
@@ -179,6 +181,9 @@ func collectDataV2(req *http.Request) (data []byte, err error) {
 		s += "\n"
 		s += fmt.Sprintf("Content-Type: %s", contentType)
 	}
+
+	//write X-Qiniu-* headers, sorted by canonical name
+	s += qiniuHeaders(req.Header)
 	s += "\n\n"
 
 	data = []byte(s)
@@ -195,6 +200,25 @@ func collectDataV2(req *http.Request) (data []byte, err error) {
 	return
 }
 
+// qiniuHeaders 返回参与V2签名的X-Qiniu-*头部， 按规范化的名字排序， 每个头部一行
+// 同名的头部只使用第一个值
+func qiniuHeaders(header http.Header) string {
+	var keys []string
+	for k := range header {
+		k = http.CanonicalHeaderKey(k)
+		if strings.HasPrefix(k, "X-Qiniu-") && len(k) > len("X-Qiniu-") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var s string
+	for _, k := range keys {
+		s += fmt.Sprintf("\n%s: %s", k, header.Get(k))
+	}
+	return s
+}
+
 // SignRequest 对数据进行签名，一般用于管理凭证的生成
 func (v *Value) SignRequest(req *http.Request) (token string, err error) {
 	data, err := collectData(req)
@@ -227,18 +251,32 @@ func incBodyV2(req *http.Request) bool {
 }
 
 // VerifyCallback 验证上传回调请求是否来自七牛
+// 支持QBox和Qiniu(V2)两种签名方式， 验证之后req.Body依然可以读取
 func (v *Value) VerifyCallback(req *http.Request) (bool, error) {
 	auth := req.Header.Get("Authorization")
 	if auth == "" {
 		return false, nil
 	}
 
-	token, err := v.SignRequest(req)
+	var (
+		token string
+		err   error
+	)
+	switch {
+	case strings.HasPrefix(auth, "QBox "):
+		token, err = v.SignRequest(req)
+		token = "QBox " + token
+	case strings.HasPrefix(auth, "Qiniu "):
+		token, err = v.SignRequestV2(req)
+		token = "Qiniu " + token
+	default:
+		return false, nil
+	}
 	if err != nil {
 		return false, err
 	}
 
-	return auth == "QBox "+token, nil
+	return hmac.Equal([]byte(auth), []byte(token)), nil
 }
 
 // IsEmpty 返回密钥信息是否为空
@@ -40,6 +40,7 @@ func Config() *qiniu.Config {
 		WithRsfHost(defs.DefaultRsfHost).
 		WithAPIHost(defs.DefaultAPIHost).
 		WithUCHost(defs.DefaultUcHost).
+		WithFusionHost(defs.DefaultFusionHost).
 		WithMorseHost(defs.DefaultMorseHost).
 		WithEmailClientID(defs.DefaultEmailClientID)
 }
@@ -43,6 +43,9 @@ const (
 	// DefaultUcHost 查询存储空间相关域名
 	DefaultUcHost = "uc.qbox.me"
 
+	// DefaultFusionHost 默认的CDN服务域名
+	DefaultFusionHost = "fusion.qiniuapi.com"
+
 	// DefaultFormSize 默认的最大的可以使用表单方式上传的文件大小
 	DefaultFormSize = 1 * MB
 
@@ -0,0 +1,147 @@
+package defs
+
+const (
+	// RegionZ0 华东
+	RegionZ0 = "z0"
+
+	// RegionZ1 华北
+	RegionZ1 = "z1"
+
+	// RegionZ2 华南
+	RegionZ2 = "z2"
+
+	// RegionNa0 北美
+	RegionNa0 = "na0"
+
+	// RegionAs0 东南亚
+	RegionAs0 = "as0"
+
+	// DefaultRegion 没有配置存储区域的时候默认使用的区域
+	DefaultRegion = RegionZ0
+)
+
+// Host 存储区域的各个服务入口的域名配置
+type Host struct {
+	// 可用的上传域名列表
+	UpHosts []string
+
+	// 可用的加速上传域名列表
+	CdnUpHosts []string
+
+	// 资源管理域名
+	RsHost string
+
+	// 资源列举域名
+	RsfHost string
+
+	// 数据处理等API域名
+	APIHost string
+
+	// 存储下载入口域名
+	IoHost string
+}
+
+// Copy 返回h的一份拷贝
+func (h *Host) Copy() *Host {
+	dst := *h
+	dst.UpHosts = append([]string(nil), h.UpHosts...)
+	dst.CdnUpHosts = append([]string(nil), h.CdnUpHosts...)
+	return &dst
+}
+
+// MergeIn 把others中非空的字段合并到h中， 后面的配置会覆盖前面的配置
+func (h *Host) MergeIn(others ...*Host) {
+	for _, other := range others {
+		if other == nil {
+			continue
+		}
+		if len(other.UpHosts) > 0 {
+			h.UpHosts = other.UpHosts
+		}
+		if len(other.CdnUpHosts) > 0 {
+			h.CdnUpHosts = other.CdnUpHosts
+		}
+		if other.RsHost != "" {
+			h.RsHost = other.RsHost
+		}
+		if other.RsfHost != "" {
+			h.RsfHost = other.RsfHost
+		}
+		if other.APIHost != "" {
+			h.APIHost = other.APIHost
+		}
+		if other.IoHost != "" {
+			h.IoHost = other.IoHost
+		}
+	}
+}
+
+// IsEmpty 所有的域名都没有配置的时候返回true
+func (h *Host) IsEmpty() bool {
+	return len(h.UpHosts) == 0 && len(h.CdnUpHosts) == 0 && h.RsHost == "" &&
+		h.RsfHost == "" && h.APIHost == "" && h.IoHost == ""
+}
+
+// Regions 返回SDK支持的所有的存储区域的名字
+func Regions() []string {
+	return []string{RegionZ0, RegionZ1, RegionZ2, RegionNa0, RegionAs0}
+}
+
+// IsValidRegion 判断region是否是SDK支持的存储区域
+func IsValidRegion(region string) bool {
+	_, ok := defaultRegionHosts[region]
+	return ok
+}
+
+// DefaultRegionHost 返回存储区域region的默认域名配置
+// 如果region不是支持的区域，第二个返回值为false
+func DefaultRegionHost(region string) (*Host, bool) {
+	h, ok := defaultRegionHosts[region]
+	if !ok {
+		return nil, false
+	}
+	return h.Copy(), true
+}
+
+var defaultRegionHosts = map[string]*Host{
+	RegionZ0: {
+		UpHosts:    []string{"up.qiniup.com", "up-nb.qiniup.com", "up-xs.qiniup.com"},
+		CdnUpHosts: []string{"upload.qiniup.com", "upload-nb.qiniup.com", "upload-xs.qiniup.com"},
+		RsHost:     "rs.qbox.me",
+		RsfHost:    "rsf.qbox.me",
+		APIHost:    "api.qiniu.com",
+		IoHost:     "iovip.qbox.me",
+	},
+	RegionZ1: {
+		UpHosts:    []string{"up-z1.qiniup.com"},
+		CdnUpHosts: []string{"upload-z1.qiniup.com"},
+		RsHost:     "rs-z1.qbox.me",
+		RsfHost:    "rsf-z1.qbox.me",
+		APIHost:    "api-z1.qiniu.com",
+		IoHost:     "iovip-z1.qbox.me",
+	},
+	RegionZ2: {
+		UpHosts:    []string{"up-z2.qiniup.com", "up-gz.qiniup.com", "up-fs.qiniup.com"},
+		CdnUpHosts: []string{"upload-z2.qiniup.com", "upload-gz.qiniup.com", "upload-fs.qiniup.com"},
+		RsHost:     "rs-z2.qbox.me",
+		RsfHost:    "rsf-z2.qbox.me",
+		APIHost:    "api-z2.qiniu.com",
+		IoHost:     "iovip-z2.qbox.me",
+	},
+	RegionNa0: {
+		UpHosts:    []string{"up-na0.qiniup.com"},
+		CdnUpHosts: []string{"upload-na0.qiniup.com"},
+		RsHost:     "rs-na0.qbox.me",
+		RsfHost:    "rsf-na0.qbox.me",
+		APIHost:    "api-na0.qiniu.com",
+		IoHost:     "iovip-na0.qbox.me",
+	},
+	RegionAs0: {
+		UpHosts:    []string{"up-as0.qiniup.com"},
+		CdnUpHosts: []string{"upload-as0.qiniup.com"},
+		RsHost:     "rs-as0.qbox.me",
+		RsfHost:    "rsf-as0.qbox.me",
+		APIHost:    "api-as0.qiniu.com",
+		IoHost:     "iovip-as0.qbox.me",
+	},
+}
@@ -48,6 +48,9 @@ const (
 
 	// LogDebugMultipartUpload 开启分片上传调试日志
 	LogDebugMultipartUpload
+
+	// LogDebugMultipartDownload 开启分段并发下载调试日志
+	LogDebugMultipartDownload
 )
 
 // Logger 是最小化的日志输出接口
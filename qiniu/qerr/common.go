@@ -26,6 +26,9 @@ const (
 	// ErrAccountFrozen -> httpStatusCode: 419, 用户账号被冻结。
 	ErrAccountFrozen = "AccountFrozenError"
 
+	// ErrRangeNotSatisfiable -> httpStatusCode: 416, 请求的Range超出了资源的范围， 比如对空资源发送Range请求。
+	ErrRangeNotSatisfiable = "RangeNotSatisfiableError"
+
 	// ErrMirrorSourceRequest -> httpStatusCode: 478, 镜像回源失败。 主要指镜像源服务器出现异常。
 	ErrMirrorSourceRequest = "MirrorSourceError"
 
@@ -75,6 +78,9 @@ const (
 	// ErrOpenFile 打开文件失败
 	ErrOpenFile = "OpenFileError"
 
+	// ErrEtagMismatch 本地计算的qetag和服务端的文件hash不一致， 数据在传输过程中被损坏
+	ErrEtagMismatch = "EtagMismatchError"
+
 	// ErrStructFieldValidation 如果监测到不符合要求的字段，就会返回该错误
 	// 有些函数或者方法对于输入的参数有要求， 比如不能是空， 不能为0等等
 	ErrStructFieldValidation = "StructFieldError"
@@ -0,0 +1,154 @@
+// Package qetag 实现了七牛的文件hash算法(qetag)
+//
+// 数据按照4MB分块， 每块计算SHA1:
+// 只有一块的时候， 结果是0x16加上这一块的SHA1;
+// 有多块的时候， 结果是0x96加上所有块的SHA1拼接起来之后的SHA1
+// 最后做URL Safe Base64编码， 和存储空间中资源的hash一致
+package qetag
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"hash"
+	"io"
+	"os"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/defs"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+)
+
+// BlockSize qetag算法的分块大小
+const BlockSize = 4 * defs.MB
+
+// Size qetag的字节数， 1字节的前缀加上20字节的SHA1
+const Size = 1 + sha1.Size
+
+// Hasher 计算qetag的hash.Hash, 可以作为io.Writer使用
+//
+//	h := qetag.New()
+//	io.Copy(h, r)
+//	etag := h.Etag()
+type Hasher struct {
+	block   hash.Hash
+	written int64
+	sums    []byte
+}
+
+// New 返回一个Hasher指针
+func New() *Hasher {
+	return &Hasher{block: sha1.New()}
+}
+
+// Write 实现了io.Writer接口， 不会返回错误
+func (h *Hasher) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		remain := BlockSize - int(h.written%BlockSize)
+		if remain > len(p) {
+			remain = len(p)
+		}
+		h.block.Write(p[:remain])
+		h.written += int64(remain)
+		p = p[remain:]
+		if h.written%BlockSize == 0 {
+			h.sums = h.block.Sum(h.sums)
+			h.block.Reset()
+		}
+	}
+	return n, nil
+}
+
+// Sum 把qetag的原始字节追加到b之后返回， 不改变Hasher的状态
+func (h *Hasher) Sum(b []byte) []byte {
+	sums := h.sums
+	if h.written == 0 || h.written%BlockSize != 0 {
+		sums = h.block.Sum(append([]byte(nil), sums...))
+	}
+	if len(sums) == sha1.Size {
+		return append(append(b, 0x16), sums...)
+	}
+	sum := sha1.Sum(sums)
+	return append(append(b, 0x96), sum[:]...)
+}
+
+// Reset 重置Hasher
+func (h *Hasher) Reset() {
+	h.block.Reset()
+	h.written = 0
+	h.sums = h.sums[:0]
+}
+
+// Size 实现了hash.Hash接口
+func (h *Hasher) Size() int {
+	return Size
+}
+
+// BlockSize 实现了hash.Hash接口
+func (h *Hasher) BlockSize() int {
+	return h.block.BlockSize()
+}
+
+// Etag 返回URL Safe Base64编码的qetag
+func (h *Hasher) Etag() string {
+	return base64.URLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Blocks 返回已经写入的数据每个分块的SHA1, 最后一个不完整的分块也包括在内
+// 按照BlockSize对齐分段计算的时候， 把各段的Blocks按顺序拼接起来传给Join得到完整数据的qetag
+func (h *Hasher) Blocks() [][]byte {
+	blocks := make([][]byte, 0, len(h.sums)/sha1.Size+1)
+	for i := 0; i < len(h.sums); i += sha1.Size {
+		blocks = append(blocks, append([]byte(nil), h.sums[i:i+sha1.Size]...))
+	}
+	if h.written%BlockSize != 0 {
+		blocks = append(blocks, h.block.Sum(nil))
+	}
+	return blocks
+}
+
+// Join 根据按顺序排列的所有分块的SHA1计算qetag, 没有分块的时候返回空数据的qetag
+func Join(blocks [][]byte) string {
+	var sum []byte
+	switch len(blocks) {
+	case 0:
+		s := sha1.Sum(nil)
+		sum = append([]byte{0x16}, s[:]...)
+	case 1:
+		sum = append([]byte{0x16}, blocks[0]...)
+	default:
+		h := sha1.New()
+		for _, b := range blocks {
+			h.Write(b)
+		}
+		sum = h.Sum([]byte{0x96})
+	}
+	return base64.URLEncoding.EncodeToString(sum)
+}
+
+// Reader 计算r中所有数据的qetag
+func Reader(r io.Reader) (string, error) {
+	h := New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return h.Etag(), nil
+}
+
+// File 计算本地文件的qetag
+func File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", qerr.New(qerr.ErrOpenFile, "failed to open file: "+path, err)
+	}
+	defer f.Close()
+
+	return Reader(f)
+}
+
+// Verify 比较计算得到的qetag和期望的hash, 不一致的时候返回qerr.ErrEtagMismatch错误
+func Verify(expected, actual string) error {
+	if expected != actual {
+		return qerr.New(qerr.ErrEtagMismatch, "etag mismatch, expected: "+expected+", actual: "+actual, nil)
+	}
+	return nil
+}
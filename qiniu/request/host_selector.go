@@ -0,0 +1,253 @@
+package request
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+)
+
+const (
+	// DefaultHostFreezeThreshold 域名连续失败多少次之后被冻结
+	DefaultHostFreezeThreshold = 3
+
+	// DefaultHostFreezeDuration 域名第一次被冻结的时长， 之后连续冻结的时长依次翻倍
+	DefaultHostFreezeDuration = 10 * time.Second
+
+	// DefaultHostMaxFreezeDuration 域名被冻结的最长时长
+	DefaultHostMaxFreezeDuration = 10 * time.Minute
+)
+
+// HostSelector 为请求的每次尝试选择域名
+// 请求因为网络错误或者5xx失败的时候， 会冻结当前的域名并换一个域名重新发送
+type HostSelector interface {
+	// SelectHost 返回本次尝试要使用的域名， 可以带上scheme
+	SelectHost() string
+
+	// Freeze 冻结请求失败的域名， 冻结期间尽量不再选择该域名
+	Freeze(host string)
+
+	// Unfreeze 请求成功后解冻该域名
+	Unfreeze(host string)
+
+	// Len 返回可以选择的域名数量， 一个请求最多切换Len()-1次域名
+	Len() int
+}
+
+// WithHostSelector 构建一个Option, 设置请求的HostSelector
+func WithHostSelector(s HostSelector) Option {
+	return func(r *Request) {
+		r.HostSelector = s
+	}
+}
+
+// WithHosts 构建一个Option, 请求失败的时候在hosts之间切换
+func WithHosts(hosts ...string) Option {
+	return WithHostSelector(NewHostSelector(hosts...))
+}
+
+// HostFreezer 记录域名连续失败的次数， 连续失败Threshold次之后冻结该域名
+// 冻结的时长从Duration开始， 解冻之后再次被冻结的时候翻倍， 最长为MaxDuration, 请求成功之后清除记录
+// 一个HostFreezer可以被多个HostSelector共用， 比如同一个客户端发出的所有请求， 零值可以直接使用
+type HostFreezer struct {
+	// 连续失败多少次之后冻结域名， 小于等于0的时候使用DefaultHostFreezeThreshold
+	Threshold int
+
+	// 第一次冻结的时长， 小于等于0的时候使用DefaultHostFreezeDuration
+	Duration time.Duration
+
+	// 最长的冻结时长， 小于等于0的时候使用DefaultHostMaxFreezeDuration
+	MaxDuration time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostFailures
+}
+
+// hostFailures 一个域名的失败记录
+type hostFailures struct {
+	// 连续失败的次数
+	count int
+
+	// 最近一次冻结的时长和解冻的时间
+	freeze time.Duration
+	until  time.Time
+}
+
+// NewHostFreezer 返回一个使用默认配置的HostFreezer指针
+func NewHostFreezer() *HostFreezer {
+	return &HostFreezer{}
+}
+
+// Fail 记录域名host的一次失败， 连续失败的次数达到Threshold的时候冻结该域名
+func (f *HostFreezer) Fail(host string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.hosts == nil {
+		f.hosts = make(map[string]*hostFailures)
+	}
+	h := f.hosts[host]
+	if h == nil {
+		h = &hostFailures{}
+		f.hosts[host] = h
+	}
+	h.count++
+	threshold := f.Threshold
+	if threshold <= 0 {
+		threshold = DefaultHostFreezeThreshold
+	}
+	if h.count < threshold {
+		return
+	}
+	h.count = 0
+	if h.freeze > 0 {
+		h.freeze *= 2
+	} else if h.freeze = f.Duration; h.freeze <= 0 {
+		h.freeze = DefaultHostFreezeDuration
+	}
+	max := f.MaxDuration
+	if max <= 0 {
+		max = DefaultHostMaxFreezeDuration
+	}
+	if h.freeze > max {
+		h.freeze = max
+	}
+	h.until = time.Now().Add(h.freeze)
+}
+
+// Succeed 清除域名host的失败记录
+func (f *HostFreezer) Succeed(host string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.hosts, host)
+}
+
+// FrozenUntil 返回域名的解冻时间， 没有被冻结的时候返回零值
+func (f *HostFreezer) FrozenUntil(host string) time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	h := f.hosts[host]
+	if h == nil || !time.Now().Before(h.until) {
+		return time.Time{}
+	}
+	return h.until
+}
+
+// ListHostSelector 按照列表的顺序选择域名， 跳过这个选择器中已经失败过的域名和被Freezer冻结的域名
+// 所有的域名都不可用的时候， 选择最早解冻的域名
+// 失败过的域名只记录在选择器中， 一般每个请求使用一个新的选择器， 需要在请求之间共享失败记录的时候共用Freezer
+type ListHostSelector struct {
+	// 记录域名的失败次数和冻结时间， 为nil的时候失败记录只在这个选择器中有效
+	Freezer *HostFreezer
+
+	hosts  []string
+	failed map[string]bool
+}
+
+// NewHostSelector 返回一个ListHostSelector指针， 重复和空的域名会被忽略
+func NewHostSelector(hosts ...string) *ListHostSelector {
+	s := &ListHostSelector{failed: make(map[string]bool)}
+	seen := make(map[string]bool)
+	for _, h := range hosts {
+		if h != "" && !seen[h] {
+			seen[h] = true
+			s.hosts = append(s.hosts, h)
+		}
+	}
+	return s
+}
+
+// frozenUntil 返回域名不可用的截止时间， 可用的时候返回零值
+func (s *ListHostSelector) frozenUntil(host string) time.Time {
+	var until time.Time
+	if s.Freezer != nil {
+		until = s.Freezer.FrozenUntil(host)
+	}
+	if s.failed[host] && until.IsZero() {
+		// 在这个选择器中失败过， 但是没有被冻结的域名排在被冻结的域名前面
+		until = time.Unix(0, 0)
+	}
+	return until
+}
+
+// SelectHost 实现了HostSelector接口
+func (s *ListHostSelector) SelectHost() string {
+	var (
+		selected string
+		earliest time.Time
+	)
+	for _, h := range s.hosts {
+		until := s.frozenUntil(h)
+		if until.IsZero() {
+			return h
+		}
+		if selected == "" || until.Before(earliest) {
+			selected, earliest = h, until
+		}
+	}
+	return selected
+}
+
+// Freeze 实现了HostSelector接口
+func (s *ListHostSelector) Freeze(host string) {
+	if s.failed == nil {
+		s.failed = make(map[string]bool)
+	}
+	s.failed[host] = true
+	if s.Freezer != nil {
+		s.Freezer.Fail(host)
+	}
+}
+
+// Unfreeze 实现了HostSelector接口
+func (s *ListHostSelector) Unfreeze(host string) {
+	delete(s.failed, host)
+	if s.Freezer != nil {
+		s.Freezer.Succeed(host)
+	}
+}
+
+// Len 实现了HostSelector接口
+func (s *ListHostSelector) Len() int {
+	return len(s.hosts)
+}
+
+// SetHost 把请求的域名设置为host, host可以带上scheme
+// 设置了HostSelector的时候， 每次尝试发送请求之前域名会被HostSelector选择的域名覆盖
+func (r *Request) SetHost(host string) {
+	setHost(r, host)
+}
+
+// setHost 把请求的域名设置为host, host可以带上scheme
+func setHost(r *Request, host string) {
+	u := r.HTTPRequest.URL
+	if strings.Contains(host, "://") {
+		if parsed, err := url.Parse(host); err == nil {
+			u.Scheme = parsed.Scheme
+			host = parsed.Host
+		}
+	}
+	u.Host = strings.TrimRight(host, "/")
+	r.HTTPRequest.Host = ""
+	SanitizeHostForHeader(r.HTTPRequest)
+}
+
+// shouldFailover 判断请求失败后是否需要换一个域名重新发送
+// 网络错误(包括域名解析失败)和5xx错误需要切换域名， 上传回调失败(579)和未实现(501)不是域名的问题
+func shouldFailover(r *Request) bool {
+	if r.Error == nil || r.Context().Err() != nil {
+		return false
+	}
+	if e, ok := r.Error.(qerr.Error); ok && e.Code() == ErrCodeCanceled {
+		return false
+	}
+	if r.HTTPResponse == nil || r.HTTPResponse.StatusCode == 0 {
+		return true
+	}
+	code := r.HTTPResponse.StatusCode
+	return code >= 500 && code != 501 && code != 579
+}
@@ -55,6 +55,13 @@ type Request struct {
 	SignedHeaderVals       http.Header
 	DisableFollowRedirects bool
 
+	// HostSelector 不为nil的时候， 每次尝试发送请求之前都会用它重新选择域名
+	// 网络错误或者5xx的时候会立即切换到其他域名， 切换域名不计入重试次数
+	HostSelector HostSelector
+
+	// AttemptHosts 每次尝试发送请求时使用的域名， 按照尝试的顺序排列
+	AttemptHosts []string
+
 	context context.Context
 
 	built bool
@@ -375,17 +382,36 @@ func (r *Request) Send() error {
 		return err
 	}
 
+	var failovers int
 	for {
 		r.Error = nil
 		r.AttemptTime = time.Now()
 
+		// Build阶段可能会设置请求的域名和HostSelector, 需要在选择域名之前完成
+		if err := r.Build(); err != nil {
+			return err
+		}
+
+		var selected string
+		if r.HostSelector != nil {
+			if selected = r.HostSelector.SelectHost(); selected != "" {
+				setHost(r, selected)
+			}
+		}
+		r.AttemptHosts = append(r.AttemptHosts, r.HTTPRequest.URL.Host)
+
 		if err := r.Sign(); err != nil {
 			debugLogReqError(r, "Sign Request", notRetrying, err)
 			return err
 		}
 
 		if err := r.sendRequest(); err == nil {
+			if selected != "" {
+				r.HostSelector.Unfreeze(selected)
+			}
 			return nil
+		} else if r.failover(selected, &failovers) {
+			continue
 		} else if !shouldRetryCancel(r.Error) {
 			return err
 		} else {
@@ -402,6 +428,29 @@ func (r *Request) Send() error {
 	}
 }
 
+// failover 请求失败后冻结当前的域名， 如果还有没有尝试过的域名， 准备切换域名重新发送请求
+func (r *Request) failover(selected string, failovers *int) bool {
+	if selected == "" || !shouldFailover(r) {
+		return false
+	}
+	r.HostSelector.Freeze(selected)
+
+	if *failovers >= r.HostSelector.Len()-1 {
+		return false
+	}
+	if !qiniu.IsReaderSeekable(r.Body) && r.HTTPRequest.Body != http.NoBody {
+		return false
+	}
+	*failovers++
+
+	if r.Config.LogLevel.Matches(qiniu.LogDebugWithRequestRetries) {
+		r.Config.Logger.Log(fmt.Sprintf("DEBUG: Request %s/%s failed on host %s, switching host, %v",
+			r.Api.Name(), r.ServiceName, selected, r.Error))
+	}
+	r.prepareRetry()
+	return true
+}
+
 func (r *Request) prepareRetry() {
 	if r.Config.LogLevel.Matches(qiniu.LogDebugWithRequestRetries) {
 		r.Config.Logger.Log(fmt.Sprintf("DEBUG: Retrying Request %s/%s, attempt %d",
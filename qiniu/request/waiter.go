@@ -0,0 +1,158 @@
+package request
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+)
+
+const (
+	// ErrCodeWaiterFailure 等待的任务失败了
+	ErrCodeWaiterFailure = "WaiterFailure"
+
+	// ErrCodeWaiterMaxAttempts 超过了最大的检查次数， 任务依然没有结束
+	ErrCodeWaiterMaxAttempts = "WaiterMaxAttemptsExceeded"
+)
+
+// WaiterState 每次检查之后任务的状态
+type WaiterState int
+
+const (
+	// WaiterRetry 任务还没有结束， 等待一段时间后再次检查
+	WaiterRetry WaiterState = iota
+
+	// WaiterSuccess 任务成功结束
+	WaiterSuccess
+
+	// WaiterFailure 任务失败， 停止等待
+	WaiterFailure
+)
+
+// WaiterDelay 返回第attempt次检查之后， 下一次检查之前需要等待的时长， attempt从1开始
+type WaiterDelay func(attempt int) time.Duration
+
+// ConstantWaiterDelay 返回一个每次都等待d的WaiterDelay
+func ConstantWaiterDelay(d time.Duration) WaiterDelay {
+	return func(int) time.Duration {
+		return d
+	}
+}
+
+// BackoffWaiterDelay 返回一个指数退避的WaiterDelay
+// 等待时长从min开始每次翻倍， 最多不超过max, 并且加上最多25%的随机抖动， 避免大量任务同时检查
+func BackoffWaiterDelay(min, max time.Duration) WaiterDelay {
+	return func(attempt int) time.Duration {
+		d := min
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			d = max
+		}
+		if d >= 4 {
+			d += time.Duration(rand.Int63n(int64(d / 4)))
+		}
+		return d
+	}
+}
+
+// WaiterOption 修改Waiter的配置
+type WaiterOption func(*Waiter)
+
+// WithWaiterMaxAttempts 设置最大的检查次数， 小于等于0的时候不限制， 由context控制等待的时长
+func WithWaiterMaxAttempts(n int) WaiterOption {
+	return func(w *Waiter) {
+		w.MaxAttempts = n
+	}
+}
+
+// WithWaiterDelay 设置两次检查之间的等待时长
+func WithWaiterDelay(delay WaiterDelay) WaiterOption {
+	return func(w *Waiter) {
+		w.Delay = delay
+	}
+}
+
+// WithWaiterRequestOptions 设置每次检查发送的请求的Option
+func WithWaiterRequestOptions(opts ...Option) WaiterOption {
+	return func(w *Waiter) {
+		w.RequestOptions = append(w.RequestOptions, opts...)
+	}
+}
+
+// Waiter 周期性地发送请求检查任务的状态， 直到任务结束， 失败， 或者context被取消
+type Waiter struct {
+	// 等待的任务的名字， 用于错误信息
+	Name string
+
+	// 最大的检查次数， 小于等于0的时候不限制
+	MaxAttempts int
+
+	// 两次检查之间的等待时长， 为nil的时候不等待
+	Delay WaiterDelay
+
+	// 每次检查的时候调用， 生成检查任务状态的请求
+	NewRequest func([]Option) (*Request, error)
+
+	// 根据请求发送的结果判断任务的状态， err是请求发送返回的错误
+	// 返回WaiterFailure的时候， err会作为返回的错误的原始错误
+	Acceptor func(req *Request, err error) WaiterState
+
+	// 每次检查发送的请求的Option
+	RequestOptions []Option
+
+	// 为nil的时候使用qiniu.SleepWithContext
+	SleepWithContext func(context.Context, time.Duration) error
+}
+
+// ApplyOptions 把opts应用到Waiter上
+func (w *Waiter) ApplyOptions(opts ...WaiterOption) {
+	for _, fn := range opts {
+		fn(w)
+	}
+}
+
+// WaitWithContext 等待任务结束， 任务成功的时候返回nil
+//
+// 任务失败返回ErrCodeWaiterFailure, 超过最大检查次数返回ErrCodeWaiterMaxAttempts,
+// ctx被取消返回ErrCodeCanceled
+func (w Waiter) WaitWithContext(ctx context.Context) error {
+	sleep := w.SleepWithContext
+	if sleep == nil {
+		sleep = qiniu.SleepWithContext
+	}
+
+	for attempt := 1; ; attempt++ {
+		req, err := w.NewRequest(w.RequestOptions)
+		if err != nil {
+			return qerr.New(ErrCodeWaiterFailure, "failed to create request for "+w.Name, err)
+		}
+		req.SetContext(ctx)
+
+		err = req.Send()
+		if err != nil && ctx.Err() != nil {
+			return qerr.New(ErrCodeCanceled, "waiter context canceled", ctx.Err())
+		}
+		switch w.Acceptor(req, err) {
+		case WaiterSuccess:
+			return nil
+		case WaiterFailure:
+			return qerr.New(ErrCodeWaiterFailure, "failed waiting for "+w.Name, err)
+		}
+
+		if w.MaxAttempts > 0 && attempt >= w.MaxAttempts {
+			return qerr.New(ErrCodeWaiterMaxAttempts, "exceeded max wait attempts for "+w.Name, nil)
+		}
+
+		var delay time.Duration
+		if w.Delay != nil {
+			delay = w.Delay(attempt)
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return qerr.New(ErrCodeCanceled, "waiter context canceled", err)
+		}
+	}
+}
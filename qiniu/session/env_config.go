@@ -1,11 +1,13 @@
 package session
 
 import (
+	"fmt"
 	"os"
 	"strings"
 
 	"github.com/QN-zhangzhuo/go-sdk/qiniu/credentials"
 	"github.com/QN-zhangzhuo/go-sdk/qiniu/defaults"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/defs"
 )
 
 // EnvProviderName provides a name of the provider when config is loaded from environment.
@@ -57,15 +59,15 @@ type envConfig struct {
 	// 环境变量: QINIU_UC_HOST
 	UcHost string
 
-	/*
-		// 各存储区域的host配置
-		// 如果特定区域的host配置和全局的配置同时存在，那么使用特定区域的值
-		Z0  defs.Host
-		Z1  defs.Host
-		Z2  defs.Host
-		Na0 defs.Host
-		As0 defs.Host
-	*/
+	// 环境变量: QINIU_FUSION_HOST
+	FusionHost string
+
+	// 各存储区域的host配置
+	// 如果特定区域的host配置和全局的配置同时存在，那么使用特定区域的值
+	//
+	//	QINIU_Z0_RS_HOST=rs.qiniu.com
+	//	QINIU_Z0_UP_HOSTS=up.qiniup.com,up-nb.qiniup.com # 多个域名之间以逗号分隔
+	Regions map[string]*defs.Host
 }
 
 var (
@@ -78,56 +80,23 @@ var (
 	ucHostEnvKey = []string{
 		"QINIU_UC_HOST",
 	}
+	fusionHostEnvKey = []string{
+		"QINIU_FUSION_HOST",
+	}
 	apiHostEnvKey = []string{
 		"QINIU_API_HOST",
 	}
-	/*
-		z0hostEnvKey = map[string][]string{
-			"rs":  []string{"QINIU_Z0_RS_HOST"},
-			"rsf": []string{"QINIU_Z0_RSF_HOST"},
-			"api": []string{"QINIU_Z0_API_HOST"},
-			"io":  []string{"QINIU_Z0_IO_HOST"},
-			// 多个上传域名之间以逗号分隔
-			"acc": []string{"QINIU_Z0_ACC_UP_HOSTS"},
-			"up":  []string{"QINIU_Z0_UP_HOSTS"},
-		}
-		z1hostEnvKey = map[string][]string{
-			"rs":  []string{"QINIU_Z1_RS_HOST"},
-			"rsf": []string{"QINIU_Z1_RSF_HOST"},
-			"api": []string{"QINIU_Z1_API_HOST"},
-			"io":  []string{"QINIU_Z1_IO_HOST"},
-			// 多个上传域名之间以逗号分隔
-			"acc": []string{"QINIU_Z1_ACC_UP_HOSTS"},
-			"up":  []string{"QINIU_Z1_UP_HOSTS"},
-		}
-		z2hostEnvKey = map[string][]string{
-			"rs":  []string{"QINIU_Z2_RS_HOST"},
-			"rsf": []string{"QINIU_Z2_RSF_HOST"},
-			"api": []string{"QINIU_Z2_API_HOST"},
-			"io":  []string{"QINIU_Z2_IO_HOST"},
-			// 多个上传域名之间以逗号分隔
-			"acc": []string{"QINIU_Z2_ACC_UP_HOSTS"},
-			"up":  []string{"QINIU_Z2_UP_HOSTS"},
-		}
-		na0hostEnvKey = map[string][]string{
-			"rs":  []string{"QINIU_NA0_RS_HOST"},
-			"rsf": []string{"QINIU_NA0_RSF_HOST"},
-			"api": []string{"QINIU_NA0_API_HOST"},
-			"io":  []string{"QINIU_NA0_IO_HOST"},
-			// 多个上传域名之间以逗号分隔
-			"acc": []string{"QINIU_NA0_ACC_UP_HOSTS"},
-			"up":  []string{"QINIU_NA0_UP_HOSTS"},
-		}
-		as0hostEnvKey = map[string][]string{
-			"rs":  []string{"QINIU_AS0_RS_HOST"},
-			"rsf": []string{"QINIU_AS0_RSF_HOST"},
-			"api": []string{"QINIU_AS0_API_HOST"},
-			"io":  []string{"QINIU_AS0_IO_HOST"},
-			// 多个上传域名之间以逗号分隔
-			"acc": []string{"QINIU_AS0_ACC_UP_HOSTS"},
-			"up":  []string{"QINIU_AS0_UP_HOSTS"},
-		}
-	*/
+	// 区域的域名配置对应的环境变量
+	// 区域的名字为大写， 比如QINIU_Z0_UP_HOSTS, QINIU_NA0_IO_HOST
+	regionHostEnvKey = map[string]string{
+		"rs":  "QINIU_%s_RS_HOST",
+		"rsf": "QINIU_%s_RSF_HOST",
+		"api": "QINIU_%s_API_HOST",
+		"io":  "QINIU_%s_IO_HOST",
+		// 多个上传域名之间以逗号分隔
+		"acc": "QINIU_%s_ACC_UP_HOSTS",
+		"up":  "QINIU_%s_UP_HOSTS",
+	}
 	credAccessEnvKey = []string{
 		"QINIU_ACCESS_KEY_ID",
 		"QINIU_ACCESS_KEY",
@@ -157,14 +126,19 @@ func loadSharedEnvConfig() envConfig {
 	setFromEnvVal(&cfg.RsfHost, rsfHostEnvKey)
 	setFromEnvVal(&cfg.APIHost, apiHostEnvKey)
 	setFromEnvVal(&cfg.UcHost, ucHostEnvKey)
+	setFromEnvVal(&cfg.FusionHost, fusionHostEnvKey)
 
-	/*
-		setFromEnvObj(&cfg.Z0, z0hostEnvKey)
-		setFromEnvObj(&cfg.Z1, z1hostEnvKey)
-		setFromEnvObj(&cfg.Z2, z2hostEnvKey)
-		setFromEnvObj(&cfg.Na0, na0hostEnvKey)
-		setFromEnvObj(&cfg.As0, as0hostEnvKey)
-	*/
+	for _, region := range defs.Regions() {
+		h := &defs.Host{}
+		setFromEnvObj(h, region)
+		if h.IsEmpty() {
+			continue
+		}
+		if cfg.Regions == nil {
+			cfg.Regions = make(map[string]*defs.Host)
+		}
+		cfg.Regions[region] = h
+	}
 
 	// Require logical grouping of credentials
 	if len(cfg.Creds.AccessKey) == 0 || len(cfg.Creds.SecretKey) == 0 {
@@ -209,15 +183,15 @@ func setFromEnvVal(dst *string, keys []string) {
 func setFromEnvListVal(dst *[]string, keys []string) {
 	for _, k := range keys {
 		if v := os.Getenv(k); len(v) > 0 {
-			*dst = strings.Split(v, ",")
+			*dst = splitHosts(v)
 			break
 		}
 	}
 }
 
-/*
-func setFromEnvObj(dst *defs.Host, m map[string][]string) {
-	for ht, ks := range m {
+func setFromEnvObj(dst *defs.Host, region string) {
+	for ht, k := range regionHostEnvKey {
+		ks := []string{fmt.Sprintf(k, strings.ToUpper(region))}
 		switch ht {
 		case "rs":
 			setFromEnvVal(&dst.RsHost, ks)
@@ -228,10 +202,9 @@ func setFromEnvObj(dst *defs.Host, m map[string][]string) {
 		case "io":
 			setFromEnvVal(&dst.IoHost, ks)
 		case "acc":
-			setFromEnvListVal(&dst.AccUpHosts, ks)
+			setFromEnvListVal(&dst.CdnUpHosts, ks)
 		case "up":
 			setFromEnvListVal(&dst.UpHosts, ks)
 		}
 	}
 }
-*/
@@ -5,6 +5,7 @@ import (
 	"github.com/QN-zhangzhuo/go-sdk/qiniu/client"
 	"github.com/QN-zhangzhuo/go-sdk/qiniu/corehandlers"
 	"github.com/QN-zhangzhuo/go-sdk/qiniu/defaults"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/defs"
 	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
 )
 
@@ -134,6 +135,30 @@ func mergeHostConfig(userCfg, defaultCfg *qiniu.Config, envCfg envConfig, shared
 	*defaultCfg.RsfHost = mergeValue(userCfg.RsfHost, &envCfg.RsfHost, &sharedCfg.RsfHost, defaultCfg.RsfHost)
 	*defaultCfg.UcHost = mergeValue(userCfg.UcHost, &envCfg.UcHost, &sharedCfg.UcHost, defaultCfg.UcHost)
 	*defaultCfg.APIHost = mergeValue(userCfg.APIHost, &envCfg.APIHost, &sharedCfg.APIHost, defaultCfg.APIHost)
+	*defaultCfg.FusionHost = mergeValue(userCfg.FusionHost, &envCfg.FusionHost, &sharedCfg.FusionHost, defaultCfg.FusionHost)
+
+	defaultCfg.RegionHosts = mergeRegionHosts(userCfg.RegionHosts, envCfg.Regions, sharedCfg.Regions)
+}
+
+// mergeRegionHosts 合并各个区域的Host配置， 同一个区域的配置按字段合并
+// 优先级顺序用户代码中配置 > 环境变量配置 > 配置文件
+// 结果中只包含有配置的区域， 区域的默认域名由各个服务自己合并，
+// 这样自动查询到的区域域名不会被默认域名覆盖
+func mergeRegionHosts(user, env, shared map[string]*defs.Host) map[string]*defs.Host {
+	hosts := make(map[string]*defs.Host)
+	for _, m := range []map[string]*defs.Host{shared, env, user} {
+		for region, h := range m {
+			if h == nil || h.IsEmpty() {
+				continue
+			}
+			if dst, ok := hosts[region]; ok {
+				dst.MergeIn(h)
+			} else {
+				hosts[region] = h.Copy()
+			}
+		}
+	}
+	return hosts
 }
 
 func mergeValue(vs ...*string) string {
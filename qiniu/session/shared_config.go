@@ -2,9 +2,11 @@ package session
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/QN-zhangzhuo/go-sdk/internal/ini"
 	"github.com/QN-zhangzhuo/go-sdk/qiniu/credentials"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/defs"
 	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
 )
 
@@ -12,13 +14,13 @@ const (
 	accessKeyIDKey  = `qiniu_access_key_id`
 	secretAccessKey = `qiniu_secret_access_key`
 
-	rsHostKey  = "qiniu_rs_host"
-	rsfHostKey = "qiniu_rsf_host"
-	apiHostKey = "qiniu_api_host"
-	ucHostKey  = "qiniu_uc_host"
+	rsHostKey     = "qiniu_rs_host"
+	rsfHostKey    = "qiniu_rsf_host"
+	apiHostKey    = "qiniu_api_host"
+	ucHostKey     = "qiniu_uc_host"
+	fusionHostKey = "qiniu_fusion_host"
 )
 
-/*
 var zoneKeys = map[string]string{
 	"rs":  "qiniu_rs_host",
 	"rsf": "qiniu_rsf_host",
@@ -27,7 +29,6 @@ var zoneKeys = map[string]string{
 	"up":  "qiniu_up_hosts",
 	"acc": "qiniu_acc_up_hosts",
 }
-*/
 
 // sharedConfig 代表SDK配置文件的配置项
 type sharedConfig struct {
@@ -39,21 +40,17 @@ type sharedConfig struct {
 	Creds credentials.Value
 
 	// Hosts配置
-	RsHost  string
-	RsfHost string
-	APIHost string
-	UcHost  string
+	RsHost     string
+	RsfHost    string
+	APIHost    string
+	UcHost     string
+	FusionHost string
 
-	/*
-		Z0  defs.Host
-		Z1  defs.Host
-		Z2  defs.Host
-		Na0 defs.Host
-		As0 defs.Host
-	*/
+	// 各个存储区域的Host配置, 对应配置文件中的[z0], [z1], [z2], [na0], [as0]
+	Regions map[string]*defs.Host
 }
 
-var defaultSections = []string{"credentials", "host"}
+var defaultSections = append([]string{"credentials", "host"}, defs.Regions()...)
 
 type sharedConfigFile struct {
 	Filename string
@@ -126,23 +123,19 @@ func (cfg *sharedConfig) setFromIniFile(section string, file sharedConfigFile) e
 		return SharedConfigSectionNotExistsError{Section: section, Err: nil}
 	}
 	switch section {
-	/*
-		case "z0":
-			h := zoneHostFromSection(section)
-			cfg.Z0 = *h
-		case "z1":
-			h := zoneHostFromSection(section)
-			cfg.Z1 = *h
-		case "z2":
-			h := zoneHostFromSection(section)
-			cfg.Z2 = *h
-		case "na0":
-			h := zoneHostFromSection(section)
-			cfg.Na0 = *h
-		case "as0":
-			h := zoneHostFromSection(section)
-			cfg.As0 = *h
-	*/
+	case defs.RegionZ0, defs.RegionZ1, defs.RegionZ2, defs.RegionNa0, defs.RegionAs0:
+		h := zoneHostFromSection(sectionStruct)
+		if h.IsEmpty() {
+			break
+		}
+		if cfg.Regions == nil {
+			cfg.Regions = make(map[string]*defs.Host)
+		}
+		if old, ok := cfg.Regions[section]; ok {
+			old.MergeIn(h)
+		} else {
+			cfg.Regions[section] = h
+		}
 	case "host":
 		cfg.hostsFromSection(sectionStruct)
 	default:
@@ -151,19 +144,28 @@ func (cfg *sharedConfig) setFromIniFile(section string, file sharedConfigFile) e
 	return nil
 }
 
-/*
 func zoneHostFromSection(section ini.Section) *defs.Host {
 	h := defs.Host{}
 	h.RsHost = section.String(zoneKeys["rs"])
 	h.RsfHost = section.String(zoneKeys["rsf"])
 	h.IoHost = section.String(zoneKeys["io"])
 	h.APIHost = section.String(zoneKeys["api"])
-	h.UpHosts = strings.Split(section.String(zoneKeys["up"]), ",")
-	h.AccUpHosts = strings.Split(section.String(zoneKeys["acc"]), ",")
+	h.UpHosts = splitHosts(section.String(zoneKeys["up"]))
+	h.CdnUpHosts = splitHosts(section.String(zoneKeys["acc"]))
 
 	return &h
 }
-*/
+
+// splitHosts 把以逗号分隔的域名列表拆分成切片， 忽略空的域名
+func splitHosts(s string) []string {
+	var hosts []string
+	for _, h := range strings.Split(s, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
 
 // hostsFromSection 从ini.Section中获取hosts信息
 func (cfg *sharedConfig) hostsFromSection(section ini.Section) {
@@ -171,6 +173,7 @@ func (cfg *sharedConfig) hostsFromSection(section ini.Section) {
 	cfg.RsfHost = section.String(rsfHostKey)
 	cfg.UcHost = section.String(ucHostKey)
 	cfg.APIHost = section.String(apiHostKey)
+	cfg.FusionHost = section.String(fusionHostKey)
 }
 
 // credsFromSection 从section中获取密钥信息， 设置cfg.Creds字段
@@ -0,0 +1,100 @@
+// Package cdn 提供了七牛CDN(fusion)服务的客户端
+//
+// 客户端基于client.BaseClient实现， 请求的重试， 日志， 签名都由request.Handlers处理
+//
+//	sess := session.Must(session.New())
+//	svc := cdn.NewService(sess)
+package cdn
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/client"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/corehandlers"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/credentials"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/defs"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/session"
+)
+
+const (
+	// ServiceName CDN服务的名字
+	ServiceName = "cdn"
+
+	// ErrFusion CDN接口返回的code不是200
+	ErrFusion = "FusionError"
+)
+
+// CDN CDN服务客户端， 接口在Config.FusionHost上
+type CDN struct {
+	*client.BaseClient
+}
+
+// New 使用默认的Session新建一个CDN实例
+func New() *CDN {
+	sess := session.Must(session.New())
+	return NewService(sess)
+}
+
+// NewService 使用ConfigProvider 新建一个CDN实例
+func NewService(p client.ConfigProvider, cfgs ...*qiniu.Config) *CDN {
+	c := p.ClientConfig(cfgs...)
+	return &CDN{
+		BaseClient: client.New(
+			*c.Config,
+			c.Handlers,
+		),
+	}
+}
+
+// fusionRet CDN接口返回的公共字段
+type fusionRet struct {
+	Code  int    `json:"code"`
+	Error string `json:"error"`
+}
+
+func (r *fusionRet) ret() *fusionRet {
+	return r
+}
+
+// fusionOutput 所有的CDN接口的输出都包含fusionRet
+type fusionOutput interface {
+	ret() *fusionRet
+}
+
+// newRequest 生成一个使用QBox签名的CDN请求， 响应中的code不是200的时候设置ErrFusion错误
+func (c *CDN) newRequest(method, path, apiName string, params interface{}, output fusionOutput) *request.Request {
+	host := qiniu.StringValue(c.Config.FusionHost)
+	if host == "" {
+		host = defs.DefaultFusionHost
+	}
+	op := &request.API{
+		Method:      method,
+		Path:        path,
+		Host:        host,
+		ContentType: defs.CONTENT_TYPE_JSON,
+		TokenType:   credentials.TokenQBox,
+		ServiceName: ServiceName,
+		APIName:     apiName,
+	}
+	req := c.NewRequest(op, params, output)
+	req.Handlers.Sign.PushBackNamed(corehandlers.QboxTokenRequestHandler)
+	req.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+		if r.Error != nil {
+			return
+		}
+		if ret := output.ret(); ret.Code != 0 && ret.Code != 200 {
+			r.Error = qerr.New(ErrFusion, fmt.Sprintf("%d: %s", ret.Code, ret.Error), nil)
+		}
+	})
+	return req
+}
+
+func sendWithContext(ctx context.Context, req *request.Request, opts ...request.Option) error {
+	req.SetContext(ctx)
+	req.ApplyOptions(opts...)
+	return req.Send()
+}
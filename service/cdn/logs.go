@@ -0,0 +1,234 @@
+package cdn
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/corehandlers"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+// LogFile 一个CDN访问日志文件， 日志文件是gzip压缩的文本， 每行一条访问记录
+type LogFile struct {
+	// 日志文件的名字
+	Name string `json:"name"`
+
+	// 压缩后的大小， 单位是字节
+	Size int64 `json:"size"`
+
+	// 最后修改时间， Unix时间戳， 单位是秒
+	Mtime int64 `json:"mtime"`
+
+	// 下载地址， 有效期有限， 需要在列举之后尽快下载
+	URL string `json:"url"`
+}
+
+// ListLogsInput 列举CDN访问日志的输入参数
+type ListLogsInput struct {
+	// 要列举的域名
+	Domains []string
+
+	// 日志的日期， 只使用日期部分， 按北京时间计算
+	Day time.Time
+}
+
+// Validate 检查输入的参数
+func (i *ListLogsInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "ListLogsInput"}
+	if len(i.Domains) == 0 {
+		invalidParams.Add(request.NewErrParamRequired("Domains"))
+	}
+	for n, d := range i.Domains {
+		if d == "" || strings.Contains(d, ";") {
+			invalidParams.Add(request.NewErrParamFormat(fmt.Sprintf("Domains[%d]", n), "domain without ';'", d))
+		}
+	}
+	if i.Day.IsZero() {
+		invalidParams.Add(request.NewErrParamRequired("Day"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// listLogsParams 列举CDN访问日志的请求体
+type listLogsParams struct {
+	Day     string `json:"day"`
+	Domains string `json:"domains"`
+}
+
+// ListLogsOutput 列举CDN访问日志的结果
+type ListLogsOutput struct {
+	fusionRet
+
+	// 每个域名的日志文件， 没有日志的域名不在结果中
+	Logs map[string][]LogFile `json:"data"`
+}
+
+// Files 返回所有域名的日志文件， 按域名排序， 同一个域名的文件保持接口返回的顺序
+func (o *ListLogsOutput) Files() []LogFile {
+	domains := make([]string, 0, len(o.Logs))
+	for d := range o.Logs {
+		domains = append(domains, d)
+	}
+	sort.Strings(domains)
+
+	var files []LogFile
+	for _, d := range domains {
+		files = append(files, o.Logs[d]...)
+	}
+	return files
+}
+
+// ListLogsRequest 生成一个列举CDN访问日志的请求
+func (c *CDN) ListLogsRequest(input *ListLogsInput) (req *request.Request, output *ListLogsOutput) {
+	if input == nil {
+		input = &ListLogsInput{}
+	}
+	output = &ListLogsOutput{}
+	params := &listLogsParams{
+		Day:     input.Day.In(fusionLocation).Format(fusionDateFormat),
+		Domains: strings.Join(input.Domains, ";"),
+	}
+	req = c.newRequest("POST", "/v2/tune/log/list", "ListLogs", params, output)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return
+}
+
+// ListLogs 列举域名某一天的CDN访问日志的下载地址
+func (c *CDN) ListLogs(input *ListLogsInput) (*ListLogsOutput, error) {
+	req, out := c.ListLogsRequest(input)
+	return out, req.Send()
+}
+
+// ListLogsWithContext 和ListLogs一样， 可以使用ctx取消请求
+func (c *CDN) ListLogsWithContext(ctx context.Context, input *ListLogsInput, opts ...request.Option) (*ListLogsOutput, error) {
+	req, out := c.ListLogsRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// OpenLogs 依次下载并解压日志文件， 返回所有文件的内容拼接起来的文本流， 每个文件的内容都以换行结束
+// 文件在读取到的时候才会下载， 使用Config.HTTPClient发送请求， 读取完之后需要调用Close
+//
+//	scanner := bufio.NewScanner(svc.OpenLogs(ctx, out.Files()))
+//	for scanner.Scan() {
+//		line := scanner.Text()
+//	}
+func (c *CDN) OpenLogs(ctx context.Context, files []LogFile) io.ReadCloser {
+	client := c.Config.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &logReader{ctx: ctx, client: client, files: files}
+}
+
+// logReader 按顺序读取多个gzip压缩的日志文件
+type logReader struct {
+	ctx    context.Context
+	client *http.Client
+	files  []LogFile
+
+	body io.ReadCloser
+	gz   *gzip.Reader
+
+	// 当前文件最后读到的字节， 文件不以换行结束的时候补一个换行
+	last    byte
+	newline bool
+	err     error
+}
+
+// open 下载下一个日志文件
+func (r *logReader) open() error {
+	f := r.files[0]
+	r.files = r.files[1:]
+
+	req, err := http.NewRequest("GET", f.URL, nil)
+	if err != nil {
+		return qerr.New(request.ErrCodeSerialization, "invalid log url: "+f.URL, err)
+	}
+	resp, err := r.client.Do(req.WithContext(r.ctx))
+	if err != nil {
+		return qerr.New("RequestError", "download log "+f.Name+" failed", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		resp.Body.Close()
+		return qerr.New(corehandlers.ErrorCode(resp.StatusCode), resp.Status+": download log "+f.Name+" failed", nil)
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return qerr.New(qerr.ErrCodeDeserialization, "log "+f.Name+" is not gzip compressed", err)
+	}
+	r.body, r.gz, r.last = resp.Body, gz, '\n'
+	return nil
+}
+
+// closeFile 关闭当前的日志文件
+func (r *logReader) closeFile() {
+	if r.gz != nil {
+		r.gz.Close()
+		r.body.Close()
+		r.gz, r.body = nil, nil
+	}
+}
+
+func (r *logReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for {
+		if r.newline {
+			r.newline = false
+			p[0] = '\n'
+			return 1, nil
+		}
+		if r.gz == nil {
+			if len(r.files) == 0 {
+				r.err = io.EOF
+				return 0, io.EOF
+			}
+			if err := r.open(); err != nil {
+				r.err = err
+				return 0, err
+			}
+		}
+		n, err := r.gz.Read(p)
+		if n > 0 {
+			r.last = p[n-1]
+			return n, nil
+		}
+		if err == io.EOF {
+			r.newline = r.last != '\n'
+			r.closeFile()
+			continue
+		}
+		if err != nil {
+			r.err = qerr.New(qerr.ErrCodeDeserialization, "decompress log failed", err)
+			r.closeFile()
+			return 0, r.err
+		}
+	}
+}
+
+// Close 关闭正在读取的日志文件， 剩下的文件不再下载
+func (r *logReader) Close() error {
+	r.closeFile()
+	r.files = nil
+	if r.err == nil {
+		r.err = io.ErrClosedPipe
+	}
+	return nil
+}
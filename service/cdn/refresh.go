@@ -0,0 +1,477 @@
+package cdn
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+const (
+	// MaxRefreshURLs 一次刷新请求最多包含的URL数量
+	MaxRefreshURLs = 60
+
+	// MaxRefreshDirs 一次刷新请求最多包含的目录数量
+	MaxRefreshDirs = 10
+
+	// MaxPrefetchURLs 一次预取请求最多包含的URL数量
+	MaxPrefetchURLs = 60
+
+	// ErrQuotaExceeded 要刷新或者预取的数量超过了当日剩余的额度
+	ErrQuotaExceeded = "QuotaExceededError"
+)
+
+// TaskState 刷新和预取任务的状态
+type TaskState string
+
+// 刷新和预取任务的状态
+const (
+	TaskProcessing TaskState = "processing"
+	TaskSuccess    TaskState = "success"
+	TaskFailure    TaskState = "failure"
+)
+
+// taskTimeFormat 查询任务的时间格式
+const taskTimeFormat = "2006-01-02 15:04:05"
+
+// Quota 刷新和预取的每日额度
+type Quota struct {
+	// URL刷新的每日额度和剩余额度
+	URLQuotaDay   int `json:"urlQuotaDay"`
+	URLSurplusDay int `json:"urlSurplusDay"`
+
+	// 目录刷新的每日额度和剩余额度
+	DirQuotaDay   int `json:"dirQuotaDay"`
+	DirSurplusDay int `json:"dirSurplusDay"`
+
+	// 预取的每日额度和剩余额度
+	PrefetchQuotaDay   int `json:"prefetchQuotaDay"`
+	PrefetchSurplusDay int `json:"prefetchSurplusDay"`
+}
+
+func validateURLs(invalidParams *request.ErrInvalidParams, field string, urls []string, max int) {
+	if len(urls) > max {
+		invalidParams.Add(request.NewErrParamFormat(field, fmt.Sprintf("at most %d items", max), fmt.Sprintf("%d items", len(urls))))
+	}
+	for n, s := range urls {
+		if u, err := url.Parse(s); err != nil || u.Scheme == "" || u.Host == "" {
+			invalidParams.Add(request.NewErrParamFormat(fmt.Sprintf("%s[%d]", field, n), "http(s)://<domain>/<path>", s))
+		}
+	}
+}
+
+// RefreshInput 刷新缓存的输入参数， 一次最多MaxRefreshURLs个URL和MaxRefreshDirs个目录
+type RefreshInput struct {
+	// 要刷新的URL
+	URLs []string `json:"urls,omitempty"`
+
+	// 要刷新的目录， 以"/"结尾， 比如"https://example.com/static/"
+	Dirs []string `json:"dirs,omitempty"`
+}
+
+// Validate 检查输入的参数
+func (i *RefreshInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "RefreshInput"}
+	if len(i.URLs) == 0 && len(i.Dirs) == 0 {
+		invalidParams.Add(request.NewErrParamRequired("URLs"))
+	}
+	validateURLs(&invalidParams, "URLs", i.URLs, MaxRefreshURLs)
+	validateURLs(&invalidParams, "Dirs", i.Dirs, MaxRefreshDirs)
+	for n, d := range i.Dirs {
+		if !strings.HasSuffix(d, "/") {
+			invalidParams.Add(request.NewErrParamFormat(fmt.Sprintf("Dirs[%d]", n), "ends with /", d))
+		}
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// RefreshOutput 刷新缓存的结果
+type RefreshOutput struct {
+	fusionRet
+
+	// 刷新任务的ID, 用于查询任务的状态
+	RequestID string `json:"requestId"`
+
+	// 格式错误或者不属于当前账号的URL和目录
+	InvalidURLs []string `json:"invalidUrls"`
+	InvalidDirs []string `json:"invalidDirs"`
+
+	// 提交之后的每日额度
+	URLQuotaDay   int `json:"urlQuotaDay"`
+	URLSurplusDay int `json:"urlSurplusDay"`
+	DirQuotaDay   int `json:"dirQuotaDay"`
+	DirSurplusDay int `json:"dirSurplusDay"`
+}
+
+// RefreshRequest 生成一个刷新缓存的请求
+func (c *CDN) RefreshRequest(input *RefreshInput) (req *request.Request, output *RefreshOutput) {
+	if input == nil {
+		input = &RefreshInput{}
+	}
+	output = &RefreshOutput{}
+	req = c.newRequest("POST", "/v2/tune/refresh", "Refresh", input, output)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return
+}
+
+// Refresh 刷新URL和目录的缓存
+func (c *CDN) Refresh(input *RefreshInput) (*RefreshOutput, error) {
+	req, out := c.RefreshRequest(input)
+	return out, req.Send()
+}
+
+// RefreshWithContext 和Refresh一样， 可以使用ctx取消请求
+func (c *CDN) RefreshWithContext(ctx context.Context, input *RefreshInput, opts ...request.Option) (*RefreshOutput, error) {
+	req, out := c.RefreshRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// PrefetchInput 预取的输入参数， 一次最多MaxPrefetchURLs个URL
+type PrefetchInput struct {
+	// 要预取的URL
+	URLs []string `json:"urls"`
+}
+
+// Validate 检查输入的参数
+func (i *PrefetchInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "PrefetchInput"}
+	if len(i.URLs) == 0 {
+		invalidParams.Add(request.NewErrParamRequired("URLs"))
+	}
+	validateURLs(&invalidParams, "URLs", i.URLs, MaxPrefetchURLs)
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// PrefetchOutput 预取的结果
+type PrefetchOutput struct {
+	fusionRet
+
+	// 预取任务的ID, 用于查询任务的状态
+	RequestID string `json:"requestId"`
+
+	// 格式错误或者不属于当前账号的URL
+	InvalidURLs []string `json:"invalidUrls"`
+
+	// 提交之后的每日额度
+	QuotaDay   int `json:"quotaDay"`
+	SurplusDay int `json:"surplusDay"`
+}
+
+// PrefetchRequest 生成一个预取的请求
+func (c *CDN) PrefetchRequest(input *PrefetchInput) (req *request.Request, output *PrefetchOutput) {
+	if input == nil {
+		input = &PrefetchInput{}
+	}
+	output = &PrefetchOutput{}
+	req = c.newRequest("POST", "/v2/tune/prefetch", "Prefetch", input, output)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return
+}
+
+// Prefetch 把URL对应的资源预取到CDN节点
+func (c *CDN) Prefetch(input *PrefetchInput) (*PrefetchOutput, error) {
+	req, out := c.PrefetchRequest(input)
+	return out, req.Send()
+}
+
+// PrefetchWithContext 和Prefetch一样， 可以使用ctx取消请求
+func (c *CDN) PrefetchWithContext(ctx context.Context, input *PrefetchInput, opts ...request.Option) (*PrefetchOutput, error) {
+	req, out := c.PrefetchRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// GetQuotaOutput 查询每日额度的结果
+type GetQuotaOutput struct {
+	fusionRet
+	Quota
+}
+
+// GetQuotaRequest 生成一个查询刷新和预取每日额度的请求
+func (c *CDN) GetQuotaRequest() (req *request.Request, output *GetQuotaOutput) {
+	output = &GetQuotaOutput{}
+	req = c.newRequest("GET", "/v2/tune/quota", "GetQuota", nil, output)
+	return
+}
+
+// GetQuota 查询刷新和预取的每日额度和剩余额度
+func (c *CDN) GetQuota() (*GetQuotaOutput, error) {
+	req, out := c.GetQuotaRequest()
+	return out, req.Send()
+}
+
+// GetQuotaWithContext 和GetQuota一样， 可以使用ctx取消请求
+func (c *CDN) GetQuotaWithContext(ctx context.Context, opts ...request.Option) (*GetQuotaOutput, error) {
+	req, out := c.GetQuotaRequest()
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// BatchRefreshInput 批量刷新的输入参数， 数量不受单次请求的限制
+type BatchRefreshInput struct {
+	URLs []string
+	Dirs []string
+
+	// 为true的时候不检查剩余额度， 直接提交
+	SkipQuotaCheck bool
+}
+
+// BatchPrefetchInput 批量预取的输入参数， 数量不受单次请求的限制
+type BatchPrefetchInput struct {
+	URLs []string
+
+	// 为true的时候不检查剩余额度， 直接提交
+	SkipQuotaCheck bool
+}
+
+// BatchOutput 批量刷新或者预取的结果
+type BatchOutput struct {
+	// 每次请求的任务ID, 按提交的顺序排列
+	RequestIDs []string
+
+	// 所有请求中格式错误或者不属于当前账号的URL和目录
+	InvalidURLs []string
+	InvalidDirs []string
+}
+
+// chunk 把items按照每组最多size个分组
+func chunk(items []string, size int) [][]string {
+	var chunks [][]string
+	for len(items) > size {
+		chunks = append(chunks, items[:size])
+		items = items[size:]
+	}
+	if len(items) > 0 {
+		chunks = append(chunks, items)
+	}
+	return chunks
+}
+
+func quotaError(kind string, want, surplus int) error {
+	return qerr.New(ErrQuotaExceeded, fmt.Sprintf("%s: %d requested, %d left today", kind, want, surplus), nil)
+}
+
+// BatchRefresh 按照单次请求的限制拆分成多个刷新请求依次提交
+// 提交之前查询剩余额度， 额度不够的时候不提交任何请求， 返回ErrQuotaExceeded错误
+// 中途失败的时候返回已经提交的任务和错误
+func (c *CDN) BatchRefresh(ctx context.Context, input *BatchRefreshInput, opts ...request.Option) (*BatchOutput, error) {
+	if !input.SkipQuotaCheck {
+		quota, err := c.GetQuotaWithContext(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if len(input.URLs) > quota.URLSurplusDay {
+			return nil, quotaError("refresh urls", len(input.URLs), quota.URLSurplusDay)
+		}
+		if len(input.Dirs) > quota.DirSurplusDay {
+			return nil, quotaError("refresh dirs", len(input.Dirs), quota.DirSurplusDay)
+		}
+	}
+
+	// URL和目录分别拆分， 同一个请求中可以同时包含两者
+	urls, dirs := chunk(input.URLs, MaxRefreshURLs), chunk(input.Dirs, MaxRefreshDirs)
+	output := &BatchOutput{}
+	for n := 0; n < len(urls) || n < len(dirs); n++ {
+		in := &RefreshInput{}
+		if n < len(urls) {
+			in.URLs = urls[n]
+		}
+		if n < len(dirs) {
+			in.Dirs = dirs[n]
+		}
+		out, err := c.RefreshWithContext(ctx, in, opts...)
+		if err != nil {
+			return output, err
+		}
+		output.RequestIDs = append(output.RequestIDs, out.RequestID)
+		output.InvalidURLs = append(output.InvalidURLs, out.InvalidURLs...)
+		output.InvalidDirs = append(output.InvalidDirs, out.InvalidDirs...)
+	}
+	return output, nil
+}
+
+// BatchPrefetch 按照单次请求的限制拆分成多个预取请求依次提交
+// 提交之前查询剩余额度， 额度不够的时候不提交任何请求， 返回ErrQuotaExceeded错误
+// 中途失败的时候返回已经提交的任务和错误
+func (c *CDN) BatchPrefetch(ctx context.Context, input *BatchPrefetchInput, opts ...request.Option) (*BatchOutput, error) {
+	if !input.SkipQuotaCheck {
+		quota, err := c.GetQuotaWithContext(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if len(input.URLs) > quota.PrefetchSurplusDay {
+			return nil, quotaError("prefetch urls", len(input.URLs), quota.PrefetchSurplusDay)
+		}
+	}
+
+	output := &BatchOutput{}
+	for _, urls := range chunk(input.URLs, MaxPrefetchURLs) {
+		out, err := c.PrefetchWithContext(ctx, &PrefetchInput{URLs: urls}, opts...)
+		if err != nil {
+			return output, err
+		}
+		output.RequestIDs = append(output.RequestIDs, out.RequestID)
+		output.InvalidURLs = append(output.InvalidURLs, out.InvalidURLs...)
+	}
+	return output, nil
+}
+
+// Task 刷新或者预取任务中一个URL的状态
+type Task struct {
+	RequestID string    `json:"requestId"`
+	URL       string    `json:"url"`
+	State     TaskState `json:"state"`
+
+	// 失败的原因
+	StateDesc string `json:"stateDesc"`
+
+	// 进度， 0到100
+	Progress int `json:"progress"`
+
+	// 刷新任务的类型， "file"或者"dir"
+	Type string `json:"type"`
+
+	CreateAt string `json:"createAt"`
+	BeginAt  string `json:"beginAt"`
+	EndAt    string `json:"endAt"`
+}
+
+// ListTasksInput 查询刷新或者预取任务的输入参数， 条件都为空的时候返回最近的任务
+type ListTasksInput struct {
+	// 刷新或者预取接口返回的任务ID
+	RequestID string `json:"requestId,omitempty"`
+
+	// 只返回这些URL的任务
+	URLs []string `json:"urls,omitempty"`
+
+	// 只返回该状态的任务
+	State TaskState `json:"state,omitempty"`
+
+	// 页码， 从1开始， 为0的时候返回第一页
+	PageNo int `json:"pageNo,omitempty"`
+
+	// 每页的数量， 为0的时候使用服务端的默认值
+	PageSize int `json:"pageSize,omitempty"`
+
+	// 任务创建时间的范围， 可以使用任意时区， 发送的时候转换为北京时间
+	StartTime time.Time `json:"-"`
+	EndTime   time.Time `json:"-"`
+}
+
+// listTasksParams 查询任务的请求体， 时间使用taskTimeFormat格式
+type listTasksParams struct {
+	*ListTasksInput
+	StartTime string `json:"startTime,omitempty"`
+	EndTime   string `json:"endTime,omitempty"`
+}
+
+// Validate 检查输入的参数
+func (i *ListTasksInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "ListTasksInput"}
+	if i.PageNo < 0 {
+		invalidParams.Add(request.NewErrParamMinValue("PageNo", 0))
+	}
+	if i.PageSize < 0 {
+		invalidParams.Add(request.NewErrParamMinValue("PageSize", 0))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// ListTasksOutput 查询刷新或者预取任务的结果
+type ListTasksOutput struct {
+	fusionRet
+
+	PageNo   int    `json:"pageNo"`
+	PageSize int    `json:"pageSize"`
+	Total    int    `json:"total"`
+	Items    []Task `json:"items"`
+}
+
+// Done 返回所有的任务是否都已经结束
+func (o *ListTasksOutput) Done() bool {
+	for _, t := range o.Items {
+		if t.State == TaskProcessing {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed 返回失败的任务
+func (o *ListTasksOutput) Failed() []Task {
+	var tasks []Task
+	for _, t := range o.Items {
+		if t.State == TaskFailure {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks
+}
+
+func (c *CDN) listTasksRequest(path, apiName string, input *ListTasksInput) (req *request.Request, output *ListTasksOutput) {
+	if input == nil {
+		input = &ListTasksInput{}
+	}
+	params := &listTasksParams{ListTasksInput: input}
+	if !input.StartTime.IsZero() {
+		params.StartTime = input.StartTime.In(fusionLocation).Format(taskTimeFormat)
+	}
+	if !input.EndTime.IsZero() {
+		params.EndTime = input.EndTime.In(fusionLocation).Format(taskTimeFormat)
+	}
+	output = &ListTasksOutput{}
+	req = c.newRequest("POST", path, apiName, params, output)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return
+}
+
+// ListRefreshTasksRequest 生成一个查询刷新任务的请求
+func (c *CDN) ListRefreshTasksRequest(input *ListTasksInput) (req *request.Request, output *ListTasksOutput) {
+	return c.listTasksRequest("/v2/tune/refresh/list", "ListRefreshTasks", input)
+}
+
+// ListRefreshTasks 查询刷新任务的状态
+func (c *CDN) ListRefreshTasks(input *ListTasksInput) (*ListTasksOutput, error) {
+	req, out := c.ListRefreshTasksRequest(input)
+	return out, req.Send()
+}
+
+// ListRefreshTasksWithContext 和ListRefreshTasks一样， 可以使用ctx取消请求
+func (c *CDN) ListRefreshTasksWithContext(ctx context.Context, input *ListTasksInput, opts ...request.Option) (*ListTasksOutput, error) {
+	req, out := c.ListRefreshTasksRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// ListPrefetchTasksRequest 生成一个查询预取任务的请求
+func (c *CDN) ListPrefetchTasksRequest(input *ListTasksInput) (req *request.Request, output *ListTasksOutput) {
+	return c.listTasksRequest("/v2/tune/prefetch/list", "ListPrefetchTasks", input)
+}
+
+// ListPrefetchTasks 查询预取任务的状态
+func (c *CDN) ListPrefetchTasks(input *ListTasksInput) (*ListTasksOutput, error) {
+	req, out := c.ListPrefetchTasksRequest(input)
+	return out, req.Send()
+}
+
+// ListPrefetchTasksWithContext 和ListPrefetchTasks一样， 可以使用ctx取消请求
+func (c *CDN) ListPrefetchTasksWithContext(ctx context.Context, input *ListTasksInput, opts ...request.Option) (*ListTasksOutput, error) {
+	req, out := c.ListPrefetchTasksRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
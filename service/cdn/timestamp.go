@@ -0,0 +1,93 @@
+package cdn
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+const (
+	// ErrTimestampExpired 时间戳防盗链地址已经过期
+	ErrTimestampExpired = "TimestampExpiredError"
+
+	// ErrTimestampSignature 时间戳防盗链地址缺少签名或者签名不正确
+	ErrTimestampSignature = "TimestampSignatureError"
+)
+
+// timestampSign 计算时间戳防盗链的签名， sign = md5(key + escapedPath + t), t是十六进制的过期时间
+func timestampSign(key, escapedPath, t string) string {
+	sum := md5.Sum([]byte(key + escapedPath + t))
+	return hex.EncodeToString(sum[:])
+}
+
+// TimestampSignURL 对rawURL进行时间戳防盗链签名， 返回在deadline之前有效的地址
+// 签名使用的路径是url.URL.EscapedPath()， 和SDK发送请求时使用的路径一致
+// rawURL中已有的查询参数会保留， sign和t参数追加在最后
+func TimestampSignURL(key, rawURL string, deadline time.Time) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", qerr.New(request.ErrCodeSerialization, "invalid url: "+rawURL, err)
+	}
+	t := strconv.FormatInt(deadline.Unix(), 16)
+	sign := timestampSign(key, u.EscapedPath(), t)
+
+	query := "sign=" + sign + "&t=" + t
+	if u.RawQuery != "" {
+		query = u.RawQuery + "&" + query
+	}
+	u.RawQuery = query
+	u.ForceQuery = false
+	return u.String(), nil
+}
+
+// TimestampURL 返回资源的时间戳防盗链地址
+// domain可以带上scheme, 没有scheme的时候使用http, 和request.API.URL拼接地址的方式一样
+// path和request.API.Path一样， 比如"/images/a b.png", 需要转义的字符会被转义， 已经转义的"%XX"保持不变，
+// 可以带上"?"开头的查询参数
+func TimestampURL(key, domain, path string, deadline time.Time) (string, error) {
+	api := &request.API{Host: domain, Path: path}
+	return TimestampSignURL(key, api.URL(), deadline)
+}
+
+// VerifyTimestampURL 校验时间戳防盗链地址， 签名不正确的时候返回ErrTimestampSignature错误，
+// 过期时间早于now的时候返回ErrTimestampExpired错误
+func VerifyTimestampURL(key, rawURL string, now time.Time) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return qerr.New(request.ErrCodeSerialization, "invalid url: "+rawURL, err)
+	}
+	return verifyTimestamp(key, u, now)
+}
+
+// VerifyTimestampRequest 使用当前时间校验请求的地址， 用于测试CDN回源或者边缘节点的行为
+func VerifyTimestampRequest(key string, r *http.Request) error {
+	return verifyTimestamp(key, r.URL, time.Now())
+}
+
+func verifyTimestamp(key string, u *url.URL, now time.Time) error {
+	query := u.Query()
+	sign, t := query.Get("sign"), query.Get("t")
+	if sign == "" || t == "" {
+		return qerr.New(ErrTimestampSignature, "missing sign or t parameter", nil)
+	}
+	deadline, err := strconv.ParseInt(t, 16, 64)
+	if err != nil {
+		return qerr.New(ErrTimestampSignature, "invalid t parameter: "+t, err)
+	}
+	expected := timestampSign(key, u.EscapedPath(), t)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(sign))) != 1 {
+		return qerr.New(ErrTimestampSignature, "signature mismatch", nil)
+	}
+	if now.Unix() > deadline {
+		return qerr.New(ErrTimestampExpired, "url expired at "+time.Unix(deadline, 0).Format(time.RFC3339), nil)
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package cdn
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+// Granularity 流量和带宽数据的时间粒度
+type Granularity string
+
+// 流量和带宽数据的时间粒度
+const (
+	Granularity5Min Granularity = "5min"
+	GranularityHour Granularity = "hour"
+	GranularityDay  Granularity = "day"
+)
+
+const (
+	// fusionDateFormat CDN接口的日期格式
+	fusionDateFormat = "2006-01-02"
+
+	// fusionTimeFormat CDN接口返回的时间点的格式
+	fusionTimeFormat = "2006-01-02 15:04:05"
+)
+
+// fusionLocation CDN接口的日期和时间都是北京时间
+var fusionLocation = time.FixedZone("CST", 8*3600)
+
+// TrafficInput 查询带宽或者流量的输入参数
+type TrafficInput struct {
+	// 要查询的域名
+	Domains []string
+
+	// 查询的日期范围， 包括StartDate和EndDate两天， 只使用日期部分
+	StartDate time.Time
+	EndDate   time.Time
+
+	// 时间粒度， 为空的时候使用GranularityDay
+	Granularity Granularity
+}
+
+// Validate 检查输入的参数
+func (i *TrafficInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "TrafficInput"}
+	if len(i.Domains) == 0 {
+		invalidParams.Add(request.NewErrParamRequired("Domains"))
+	}
+	for n, d := range i.Domains {
+		if d == "" || strings.Contains(d, ";") {
+			invalidParams.Add(request.NewErrParamFormat(fmt.Sprintf("Domains[%d]", n), "domain without ';'", d))
+		}
+	}
+	switch i.Granularity {
+	case "", Granularity5Min, GranularityHour, GranularityDay:
+	default:
+		invalidParams.Add(request.NewErrParamFormat("Granularity", "5min, hour or day", string(i.Granularity)))
+	}
+	if i.StartDate.IsZero() {
+		invalidParams.Add(request.NewErrParamRequired("StartDate"))
+	}
+	if i.EndDate.IsZero() {
+		invalidParams.Add(request.NewErrParamRequired("EndDate"))
+	} else if i.EndDate.Before(i.StartDate) {
+		invalidParams.Add(request.NewErrParamFormat("EndDate", "not before StartDate", i.EndDate.Format(fusionDateFormat)))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+func (i *TrafficInput) granularity() Granularity {
+	if i.Granularity == "" {
+		return GranularityDay
+	}
+	return i.Granularity
+}
+
+// trafficParams 查询带宽或者流量的请求体
+type trafficParams struct {
+	StartDate   string `json:"startDate"`
+	EndDate     string `json:"endDate"`
+	Granularity string `json:"granularity"`
+	Domains     string `json:"domains"`
+}
+
+// domainTraffic 接口返回的一个域名的数据
+type domainTraffic struct {
+	China   []int64 `json:"china"`
+	Oversea []int64 `json:"oversea"`
+}
+
+// trafficRet 带宽和流量接口的返回值
+type trafficRet struct {
+	fusionRet
+	Time []string                  `json:"time"`
+	Data map[string]*domainTraffic `json:"data"`
+}
+
+// TrafficPoint 带宽或者流量数据的一个点
+type TrafficPoint struct {
+	// 统计时间段的开始时间， 北京时间
+	Time time.Time
+
+	// 国内和海外的数据， 带宽的单位是bps, 流量的单位是字节
+	China   int64
+	Oversea int64
+}
+
+// Total 返回国内和海外的和
+func (p TrafficPoint) Total() int64 {
+	return p.China + p.Oversea
+}
+
+// TrafficOutput 查询带宽或者流量的结果
+type TrafficOutput struct {
+	Granularity Granularity
+
+	// 每个域名按时间排序的数据， 没有数据的域名不在结果中
+	Domains map[string][]TrafficPoint
+}
+
+// Sum 返回domain所有时间点的和， 适用于流量
+func (o *TrafficOutput) Sum(domain string) int64 {
+	var sum int64
+	for _, p := range o.Domains[domain] {
+		sum += p.Total()
+	}
+	return sum
+}
+
+// Peak 返回domain的峰值， 适用于带宽
+func (o *TrafficOutput) Peak(domain string) TrafficPoint {
+	var peak TrafficPoint
+	for _, p := range o.Domains[domain] {
+		if p.Total() > peak.Total() {
+			peak = p
+		}
+	}
+	return peak
+}
+
+// decode 把接口返回的数据转换成每个域名的时间序列
+func (o *TrafficOutput) decode(ret *trafficRet) error {
+	times := make([]time.Time, len(ret.Time))
+	for n, s := range ret.Time {
+		t, err := time.ParseInLocation(fusionTimeFormat, s, fusionLocation)
+		if err != nil {
+			return err
+		}
+		times[n] = t
+	}
+	o.Domains = make(map[string][]TrafficPoint, len(ret.Data))
+	for domain, d := range ret.Data {
+		if d == nil {
+			continue
+		}
+		points := make([]TrafficPoint, len(times))
+		for n, t := range times {
+			points[n].Time = t
+			if n < len(d.China) {
+				points[n].China = d.China[n]
+			}
+			if n < len(d.Oversea) {
+				points[n].Oversea = d.Oversea[n]
+			}
+		}
+		o.Domains[domain] = points
+	}
+	return nil
+}
+
+func (c *CDN) trafficRequest(path, apiName string, input *TrafficInput) (req *request.Request, output *TrafficOutput) {
+	if input == nil {
+		input = &TrafficInput{}
+	}
+	output = &TrafficOutput{Granularity: input.granularity()}
+	params := &trafficParams{
+		StartDate:   input.StartDate.In(fusionLocation).Format(fusionDateFormat),
+		EndDate:     input.EndDate.In(fusionLocation).Format(fusionDateFormat),
+		Granularity: string(input.granularity()),
+		Domains:     strings.Join(input.Domains, ";"),
+	}
+	ret := &trafficRet{}
+	req = c.newRequest("POST", path, apiName, params, ret)
+	req.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+		if r.Error != nil {
+			return
+		}
+		if err := output.decode(ret); err != nil {
+			r.Error = qerr.New(qerr.ErrCodeDeserialization, "failed to decode traffic data", err)
+		}
+	})
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return
+}
+
+// GetBandwidthRequest 生成一个查询带宽的请求
+func (c *CDN) GetBandwidthRequest(input *TrafficInput) (req *request.Request, output *TrafficOutput) {
+	return c.trafficRequest("/v2/tune/bandwidth", "GetBandwidth", input)
+}
+
+// GetBandwidth 查询域名的带宽， 单位是bps
+func (c *CDN) GetBandwidth(input *TrafficInput) (*TrafficOutput, error) {
+	req, out := c.GetBandwidthRequest(input)
+	return out, req.Send()
+}
+
+// GetBandwidthWithContext 和GetBandwidth一样， 可以使用ctx取消请求
+func (c *CDN) GetBandwidthWithContext(ctx context.Context, input *TrafficInput, opts ...request.Option) (*TrafficOutput, error) {
+	req, out := c.GetBandwidthRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// GetFluxRequest 生成一个查询流量的请求
+func (c *CDN) GetFluxRequest(input *TrafficInput) (req *request.Request, output *TrafficOutput) {
+	return c.trafficRequest("/v2/tune/flux", "GetFlux", input)
+}
+
+// GetFlux 查询域名的流量， 单位是字节
+func (c *CDN) GetFlux(input *TrafficInput) (*TrafficOutput, error) {
+	req, out := c.GetFluxRequest(input)
+	return out, req.Send()
+}
+
+// GetFluxWithContext 和GetFlux一样， 可以使用ctx取消请求
+func (c *CDN) GetFluxWithContext(ctx context.Context, input *TrafficInput, opts ...request.Option) (*TrafficOutput, error) {
+	req, out := c.GetFluxRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
@@ -0,0 +1,70 @@
+// Package kodo 提供了七牛对象存储服务的客户端
+//
+// 客户端基于client.BaseClient实现， 请求的重试， 日志， 签名都由request.Handlers处理
+//
+//	sess := session.Must(session.New())
+//	svc := kodo.NewService(sess)
+package kodo
+
+import (
+	"github.com/QN-zhangzhuo/go-sdk/qiniu"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/client"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/corehandlers"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/credentials"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/session"
+)
+
+const (
+	// ServiceName 对象存储服务的名字
+	ServiceName = "kodo"
+)
+
+// Kodo 对象存储服务客户端
+type Kodo struct {
+	*client.BaseClient
+
+	// Resolver 查询存储空间所在的区域， 接口输入和Config都没有设置Region的时候使用
+	// 多个Kodo实例可以共用一个Resolver, 设置为nil的时候禁用区域查询
+	Resolver *RegionResolver
+
+	// HostFreezer 记录这个客户端的请求在各个域名上连续失败的次数， 连续失败多次的域名会被冻结一段时间
+	// 设置为nil的时候， 失败记录只在单个请求中有效
+	HostFreezer *request.HostFreezer
+}
+
+// New 使用默认的Session新建一个Kodo实例
+func New() *Kodo {
+	sess := session.Must(session.New())
+	return NewService(sess)
+}
+
+// NewService 使用ConfigProvider 新建一个Kodo实例
+func NewService(p client.ConfigProvider, cfgs ...*qiniu.Config) *Kodo {
+	c := p.ClientConfig(cfgs...)
+	svc := &Kodo{
+		BaseClient: client.New(
+			*c.Config,
+			c.Handlers,
+		),
+	}
+	svc.Resolver = NewRegionResolver(svc, qiniu.StringValue(c.Config.RegionCacheFile))
+	svc.HostFreezer = request.NewHostFreezer()
+	return svc
+}
+
+// newRequest 根据API的TokenType给请求加上相应的签名handler
+func (c *Kodo) newRequest(op *request.API, params interface{}, data interface{}) *request.Request {
+	if op.ServiceName == "" {
+		op.ServiceName = ServiceName
+	}
+	req := c.NewRequest(op, params, data)
+
+	switch op.TokenType {
+	case credentials.TokenQBox:
+		req.Handlers.Sign.PushBackNamed(corehandlers.QboxTokenRequestHandler)
+	case credentials.TokenQiniu:
+		req.Handlers.Sign.PushBackNamed(corehandlers.QiniuTokenRequestHandler)
+	}
+	return req
+}
@@ -0,0 +1,269 @@
+package kodo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/corehandlers"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/credentials"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/defs"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+const (
+	// MaxBatchOperations 一次批量请求最多可以包含的操作数
+	MaxBatchOperations = 1000
+
+	// DefaultBatchConcurrency 操作数超过MaxBatchOperations时， 默认同时发送的批量请求数
+	DefaultBatchConcurrency = 4
+)
+
+// BatchOperation 批量操作中的一个操作
+// *StatInput, *DeleteInput, *CopyInput, *MoveInput, *ChangeMimeInput, *ChangeTypeInput,
+// *DeleteAfterDaysInput, *RestoreArInput都实现了该接口， 操作中的Region字段会被忽略
+type BatchOperation interface {
+	rsOperation
+}
+
+// BatchInput 批量操作的输入参数
+type BatchInput struct {
+	// 要执行的操作， 超过MaxBatchOperations个的时候Batch会自动拆分成多个请求
+	Operations []BatchOperation
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+
+	// 同时发送的批量请求数， 小于等于0的时候使用DefaultBatchConcurrency
+	Concurrency int
+}
+
+// Validate 检查输入的参数
+func (i *BatchInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "BatchInput"}
+	if len(i.Operations) == 0 {
+		invalidParams.Add(request.NewErrParamRequired("Operations"))
+	}
+	for n, op := range i.Operations {
+		if op == nil {
+			invalidParams.Add(request.NewErrParamRequired(fmt.Sprintf("Operations[%d]", n)))
+			continue
+		}
+		if err := op.Validate(); err != nil {
+			if e, ok := err.(request.ErrInvalidParams); ok {
+				invalidParams.AddNested(fmt.Sprintf("Operations[%d]", n), e)
+			} else {
+				return err
+			}
+		}
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// BatchResult 批量操作中一个操作的结果
+type BatchResult struct {
+	// 操作的状态码， 200表示成功
+	// 整个批量请求失败的时候为0， Error为请求的错误信息
+	Code int
+
+	// 操作返回的数据， 比如stat操作的资源元信息
+	Data json.RawMessage
+
+	// 操作失败时的错误信息， 错误码和单个请求的状态码对应关系一致
+	Error error
+}
+
+// Stat 把stat操作的结果解析为StatOutput
+func (r *BatchResult) Stat() (*StatOutput, error) {
+	if r.Error != nil {
+		return nil, r.Error
+	}
+	var output StatOutput
+	if err := json.Unmarshal(r.Data, &output); err != nil {
+		return nil, qerr.New(qerr.ErrCodeDeserialization, "failed to decode stat result", err)
+	}
+	return &output, nil
+}
+
+// BatchOutput 批量操作的结果
+type BatchOutput struct {
+	// 每个操作的结果， 和BatchInput.Operations中的操作一一对应
+	Results []BatchResult
+}
+
+// Failed 返回失败的操作在Results中的下标
+func (o *BatchOutput) Failed() []int {
+	var failed []int
+	for i, r := range o.Results {
+		if r.Error != nil {
+			failed = append(failed, i)
+		}
+	}
+	return failed
+}
+
+type batchRet struct {
+	Code int             `json:"code"`
+	Data json.RawMessage `json:"data"`
+}
+
+// batchValidateResponseHandler 部分操作失败的时候接口返回298， 响应体中依然有每个操作的结果
+// 所以298不作为错误处理， 其他状态码交给core.ValidateResponseHandler
+var batchValidateResponseHandler = request.NamedHandler{Name: "kodo.BatchValidateResponseHandler", Fn: func(r *request.Request) {
+	if r.HTTPResponse.StatusCode == 298 {
+		return
+	}
+	corehandlers.ValidateResponseHandler.Fn(r)
+}}
+
+// BatchRequest 生成一个批量操作的请求， 操作数不能超过MaxBatchOperations
+// 需要自动拆分的时候使用Batch或者BatchWithContext
+//
+// 响应的状态码为298(部分操作失败)的时候请求不返回错误， 每个操作的结果在output.Results中
+func (m *BucketManager) BatchRequest(input *BatchInput) (req *request.Request, output *BatchOutput) {
+	if input == nil {
+		input = &BatchInput{}
+	}
+	output = &BatchOutput{}
+	req = m.batchRequest(input.Region, input.Operations, output)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	} else if len(input.Operations) > MaxBatchOperations {
+		req.Error = qerr.New(qerr.ErrParams, fmt.Sprintf("too many operations: %d, max: %d", len(input.Operations), MaxBatchOperations), nil)
+	}
+	return
+}
+
+// batchRequest 生成包含ops的批量请求， 调用方负责检查ops
+func (m *BucketManager) batchRequest(region string, ops []BatchOperation, output *BatchOutput) *request.Request {
+	// 批量操作中的存储空间需要在同一个区域， 使用第一个操作的存储空间查询区域
+	t := target{Region: region}
+	for _, o := range ops {
+		if o != nil {
+			t.Bucket = o.bucketName()
+			break
+		}
+	}
+	op := &request.API{
+		Method:      "POST",
+		Path:        "/batch",
+		ContentType: defs.CONTENT_TYPE_FORM,
+		TokenType:   credentials.TokenQBox,
+		ServiceName: ServiceName,
+		APIName:     "Batch",
+	}
+
+	v := make(url.Values)
+	for _, o := range ops {
+		if o != nil {
+			v.Add("op", o.opPath())
+		}
+	}
+	var rets []batchRet
+	req := m.newRequest(op, strings.NewReader(v.Encode()), &rets)
+	m.setHosts(req, t, oneHost(m.rsHost))
+	req.Handlers.ValidateResponse.Swap(corehandlers.ValidateResponseHandler.Name, batchValidateResponseHandler)
+	req.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+		if r.Error != nil {
+			return
+		}
+		if len(rets) != len(ops) {
+			r.Error = qerr.New(qerr.ErrCodeDeserialization,
+				fmt.Sprintf("batch results count mismatch, expected: %d, got: %d", len(ops), len(rets)), nil)
+			return
+		}
+		output.Results = make([]BatchResult, len(rets))
+		for i, ret := range rets {
+			output.Results[i] = newBatchResult(ret, r.RequestID)
+		}
+	})
+	return req
+}
+
+func newBatchResult(ret batchRet, reqID string) BatchResult {
+	result := BatchResult{Code: ret.Code, Data: ret.Data}
+	if ret.Code/100 != 2 {
+		var em corehandlers.ErrMsg
+		json.Unmarshal(ret.Data, &em)
+		msg := fmt.Sprintf("%d", ret.Code)
+		if em.Err != "" {
+			msg += ": " + em.Err
+		}
+		result.Error = qerr.NewRequestFailure(qerr.New(corehandlers.ErrorCode(ret.Code), msg, nil), ret.Code, reqID)
+	}
+	return result
+}
+
+// Batch 执行批量操作， 操作数超过MaxBatchOperations时自动拆分成多个请求并发发送
+//
+// 部分操作失败不会返回错误， 需要检查每个操作的BatchResult.Error
+// 某个批量请求整体失败的时候， 该请求包含的操作的Error都会设置为请求的错误， 并且返回第一个失败的请求的错误
+func (m *BucketManager) Batch(input *BatchInput) (*BatchOutput, error) {
+	return m.BatchWithContext(context.Background(), input)
+}
+
+// BatchWithContext 和Batch一样， 可以使用ctx取消请求， opts会应用到每个批量请求上
+func (m *BucketManager) BatchWithContext(ctx context.Context, input *BatchInput, opts ...request.Option) (*BatchOutput, error) {
+	if input == nil || len(input.Operations) <= MaxBatchOperations {
+		req, output := m.BatchRequest(input)
+		return output, sendWithContext(ctx, req, opts...)
+	}
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	concurrency := input.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	ops := input.Operations
+	output := &BatchOutput{Results: make([]BatchResult, len(ops))}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		sem      = make(chan struct{}, concurrency)
+	)
+	for start := 0; start < len(ops); start += MaxBatchOperations {
+		end := start + MaxBatchOperations
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(start, end int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			chunk := &BatchOutput{}
+			req := m.batchRequest(input.Region, ops[start:end], chunk)
+			if err := sendWithContext(ctx, req, opts...); err != nil {
+				for i := start; i < end; i++ {
+					output.Results[i] = BatchResult{Error: err}
+				}
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				return
+			}
+			copy(output.Results[start:end], chunk.Results)
+		}(start, end)
+	}
+	wg.Wait()
+
+	return output, firstErr
+}
@@ -0,0 +1,550 @@
+package kodo
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"unicode/utf8"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/credentials"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/defs"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+const (
+	// MaxBucketTags 一个存储空间最多可以设置的标签数量
+	MaxBucketTags = 10
+
+	// MaxBucketTagKeyLen 标签的键的最大长度， 单位为字节
+	MaxBucketTagKeyLen = 64
+
+	// MaxBucketTagValueLen 标签的值的最大长度， 单位为字节
+	MaxBucketTagValueLen = 128
+)
+
+// ucRequest 生成一个发送到Config.UcHost的存储空间管理请求， 使用QBox签名
+func (m *BucketManager) ucRequest(method, apiName, path string, params, data interface{}) *request.Request {
+	op := &request.API{
+		Method:      method,
+		Path:        path,
+		Host:        qiniu.StringValue(m.Config.UcHost),
+		ContentType: defs.CONTENT_TYPE_FORM,
+		TokenType:   credentials.TokenQBox,
+		ServiceName: ServiceName,
+		APIName:     apiName,
+	}
+	if params != nil {
+		op.ContentType = defs.CONTENT_TYPE_JSON
+	}
+	req := m.newRequest(op, params, data)
+	if op.Host == "" {
+		req.Error = qerr.New(ErrNoAvailableHost, "no uc host configured", nil)
+	}
+	return req
+}
+
+// CreateBucketInput 创建存储空间的输入参数
+type CreateBucketInput struct {
+	// 存储空间的名字， 全局唯一
+	Bucket string
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region, 都没有设置的时候使用defs.DefaultRegion
+	Region string
+}
+
+// Validate 检查输入的参数
+func (i *CreateBucketInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "CreateBucketInput"}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// CreateBucketRequest 生成一个创建存储空间的请求
+// 存储空间已经存在的时候返回qerr.ErrResourceExist, 存储空间数量达到上限的时候返回qerr.ErrStorageLimit
+func (m *BucketManager) CreateBucketRequest(input *CreateBucketInput) *request.Request {
+	if input == nil {
+		input = &CreateBucketInput{}
+	}
+	path := fmt.Sprintf("/mkbucketv3/%s/region/%s", url.PathEscape(input.Bucket), m.regionName(input.Region))
+	req := m.ucRequest("POST", "CreateBucket", path, nil, nil)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return req
+}
+
+// CreateBucket 在指定的区域创建存储空间
+func (m *BucketManager) CreateBucket(input *CreateBucketInput) error {
+	return m.CreateBucketRequest(input).Send()
+}
+
+// CreateBucketWithContext 和CreateBucket一样， 可以使用ctx取消请求
+func (m *BucketManager) CreateBucketWithContext(ctx context.Context, input *CreateBucketInput, opts ...request.Option) error {
+	return sendWithContext(ctx, m.CreateBucketRequest(input), opts...)
+}
+
+// DropBucketInput 删除存储空间的输入参数
+type DropBucketInput struct {
+	// 存储空间的名字
+	Bucket string
+}
+
+// Validate 检查输入的参数
+func (i *DropBucketInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "DropBucketInput"}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// DropBucketRequest 生成一个删除存储空间的请求， 存储空间中还有资源的时候不能删除
+// 存储空间不存在的时候返回qerr.ErrStorageNotExist
+func (m *BucketManager) DropBucketRequest(input *DropBucketInput) *request.Request {
+	if input == nil {
+		input = &DropBucketInput{}
+	}
+	req := m.ucRequest("POST", "DropBucket", "/drop/"+url.PathEscape(input.Bucket), nil, nil)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	// 删除成功之后， 缓存的存储空间区域已经没有意义了
+	req.Handlers.Complete.PushBack(func(r *request.Request) {
+		if r.Error != nil || m.Resolver == nil {
+			return
+		}
+		if ak, err := m.accessKey(""); err == nil {
+			m.Resolver.Invalidate(ak, input.Bucket)
+		}
+	})
+	return req
+}
+
+// DropBucket 删除存储空间
+func (m *BucketManager) DropBucket(input *DropBucketInput) error {
+	return m.DropBucketRequest(input).Send()
+}
+
+// DropBucketWithContext 和DropBucket一样， 可以使用ctx取消请求
+func (m *BucketManager) DropBucketWithContext(ctx context.Context, input *DropBucketInput, opts ...request.Option) error {
+	return sendWithContext(ctx, m.DropBucketRequest(input), opts...)
+}
+
+// ListBucketsInput 列举存储空间的输入参数
+type ListBucketsInput struct {
+	// 只列举该区域的存储空间， 为空的时候列举所有区域的存储空间
+	Region string
+}
+
+// ListBucketsOutput 列举存储空间的结果
+type ListBucketsOutput struct {
+	// 存储空间的名字
+	Buckets []string
+}
+
+// ListBucketsRequest 生成一个列举存储空间的请求
+func (m *BucketManager) ListBucketsRequest(input *ListBucketsInput) (req *request.Request, output *ListBucketsOutput) {
+	if input == nil {
+		input = &ListBucketsInput{}
+	}
+	output = &ListBucketsOutput{}
+	path := "/buckets"
+	if input.Region != "" {
+		path += "?region=" + url.QueryEscape(input.Region)
+	}
+	req = m.ucRequest("GET", "ListBuckets", path, nil, &output.Buckets)
+	return
+}
+
+// ListBuckets 列举账号下的存储空间
+func (m *BucketManager) ListBuckets(input *ListBucketsInput) (*ListBucketsOutput, error) {
+	req, out := m.ListBucketsRequest(input)
+	return out, req.Send()
+}
+
+// ListBucketsWithContext 和ListBuckets一样， 可以使用ctx取消请求
+func (m *BucketManager) ListBucketsWithContext(ctx context.Context, input *ListBucketsInput, opts ...request.Option) (*ListBucketsOutput, error) {
+	req, out := m.ListBucketsRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// ListDomainsInput 列举存储空间绑定的域名的输入参数
+type ListDomainsInput struct {
+	// 存储空间的名字
+	Bucket string
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+}
+
+// Validate 检查输入的参数
+func (i *ListDomainsInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "ListDomainsInput"}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// ListDomainsOutput 列举存储空间绑定的域名的结果
+type ListDomainsOutput struct {
+	// 存储空间绑定的域名， 包括测试域名
+	Domains []string
+}
+
+// ListDomainsRequest 生成一个列举存储空间绑定的域名的请求， 请求发送到APIHost
+func (m *BucketManager) ListDomainsRequest(input *ListDomainsInput) (req *request.Request, output *ListDomainsOutput) {
+	if input == nil {
+		input = &ListDomainsInput{}
+	}
+	output = &ListDomainsOutput{}
+	op := &request.API{
+		Method:      "GET",
+		Path:        "/v6/domain/list?tbl=" + url.QueryEscape(input.Bucket),
+		TokenType:   credentials.TokenQBox,
+		ServiceName: ServiceName,
+		APIName:     "ListDomains",
+	}
+	req = m.newRequest(op, nil, &output.Domains)
+	m.setHosts(req, target{Region: input.Region, Bucket: input.Bucket}, oneHost(m.apiHost))
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return
+}
+
+// ListDomains 列举存储空间绑定的域名
+func (m *BucketManager) ListDomains(input *ListDomainsInput) (*ListDomainsOutput, error) {
+	req, out := m.ListDomainsRequest(input)
+	return out, req.Send()
+}
+
+// ListDomainsWithContext 和ListDomains一样， 可以使用ctx取消请求
+func (m *BucketManager) ListDomainsWithContext(ctx context.Context, input *ListDomainsInput, opts ...request.Option) (*ListDomainsOutput, error) {
+	req, out := m.ListDomainsRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// SetBucketPrivateInput 设置存储空间访问权限的输入参数
+type SetBucketPrivateInput struct {
+	// 存储空间的名字
+	Bucket string
+
+	// 为true的时候设置为私有空间， 下载资源需要使用私有下载地址， 为false的时候设置为公开空间
+	Private bool
+}
+
+// Validate 检查输入的参数
+func (i *SetBucketPrivateInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "SetBucketPrivateInput"}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// SetBucketPrivateRequest 生成一个设置存储空间访问权限的请求
+func (m *BucketManager) SetBucketPrivateRequest(input *SetBucketPrivateInput) *request.Request {
+	if input == nil {
+		input = &SetBucketPrivateInput{}
+	}
+	v := make(url.Values)
+	v.Set("bucket", input.Bucket)
+	v.Set("private", "0")
+	if input.Private {
+		v.Set("private", "1")
+	}
+	req := m.ucRequest("POST", "SetBucketPrivate", "/private?"+v.Encode(), nil, nil)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return req
+}
+
+// SetBucketPrivate 设置存储空间为私有空间或者公开空间
+func (m *BucketManager) SetBucketPrivate(input *SetBucketPrivateInput) error {
+	return m.SetBucketPrivateRequest(input).Send()
+}
+
+// SetBucketPrivateWithContext 和SetBucketPrivate一样， 可以使用ctx取消请求
+func (m *BucketManager) SetBucketPrivateWithContext(ctx context.Context, input *SetBucketPrivateInput, opts ...request.Option) error {
+	return sendWithContext(ctx, m.SetBucketPrivateRequest(input), opts...)
+}
+
+// BucketTag 存储空间的标签
+type BucketTag struct {
+	Key   string `json:"Key"`
+	Value string `json:"Value"`
+}
+
+// SetBucketTagsInput 设置存储空间标签的输入参数
+type SetBucketTagsInput struct {
+	// 存储空间的名字
+	Bucket string `json:"-"`
+
+	// 存储空间的标签， 会覆盖原有的所有标签， 最多MaxBucketTags个
+	Tags []BucketTag `json:"Tags"`
+}
+
+// Validate 检查输入的参数
+func (i *SetBucketTagsInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "SetBucketTagsInput"}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if len(i.Tags) == 0 {
+		invalidParams.Add(request.NewErrParamRequired("Tags"))
+	}
+	if len(i.Tags) > MaxBucketTags {
+		invalidParams.Add(request.NewErrParamFormat("Tags", fmt.Sprintf("at most %d tags", MaxBucketTags), fmt.Sprint(len(i.Tags))))
+	}
+	seen := make(map[string]bool, len(i.Tags))
+	for n, t := range i.Tags {
+		field := fmt.Sprintf("Tags[%d]", n)
+		switch {
+		case t.Key == "":
+			invalidParams.Add(request.NewErrParamRequired(field + ".Key"))
+		case len(t.Key) > MaxBucketTagKeyLen || !utf8.ValidString(t.Key):
+			invalidParams.Add(request.NewErrParamFormat(field+".Key", fmt.Sprintf("utf-8, at most %d bytes", MaxBucketTagKeyLen), t.Key))
+		case seen[t.Key]:
+			invalidParams.Add(request.NewErrParamFormat(field+".Key", "unique key", t.Key))
+		}
+		if len(t.Value) > MaxBucketTagValueLen || !utf8.ValidString(t.Value) {
+			invalidParams.Add(request.NewErrParamFormat(field+".Value", fmt.Sprintf("utf-8, at most %d bytes", MaxBucketTagValueLen), t.Value))
+		}
+		seen[t.Key] = true
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// SetBucketTagsRequest 生成一个设置存储空间标签的请求
+func (m *BucketManager) SetBucketTagsRequest(input *SetBucketTagsInput) *request.Request {
+	if input == nil {
+		input = &SetBucketTagsInput{}
+	}
+	req := m.ucRequest("PUT", "SetBucketTags", "/bucketTagging?bucket="+url.QueryEscape(input.Bucket), input, nil)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return req
+}
+
+// SetBucketTags 设置存储空间的标签
+func (m *BucketManager) SetBucketTags(input *SetBucketTagsInput) error {
+	return m.SetBucketTagsRequest(input).Send()
+}
+
+// SetBucketTagsWithContext 和SetBucketTags一样， 可以使用ctx取消请求
+func (m *BucketManager) SetBucketTagsWithContext(ctx context.Context, input *SetBucketTagsInput, opts ...request.Option) error {
+	return sendWithContext(ctx, m.SetBucketTagsRequest(input), opts...)
+}
+
+// GetBucketTagsInput 获取存储空间标签的输入参数
+type GetBucketTagsInput struct {
+	// 存储空间的名字
+	Bucket string
+}
+
+// Validate 检查输入的参数
+func (i *GetBucketTagsInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "GetBucketTagsInput"}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// GetBucketTagsOutput 获取存储空间标签的结果
+type GetBucketTagsOutput struct {
+	Tags []BucketTag `json:"Tags"`
+}
+
+// Map 把标签转换为map
+func (o *GetBucketTagsOutput) Map() map[string]string {
+	m := make(map[string]string, len(o.Tags))
+	for _, t := range o.Tags {
+		m[t.Key] = t.Value
+	}
+	return m
+}
+
+// GetBucketTagsRequest 生成一个获取存储空间标签的请求
+func (m *BucketManager) GetBucketTagsRequest(input *GetBucketTagsInput) (req *request.Request, output *GetBucketTagsOutput) {
+	if input == nil {
+		input = &GetBucketTagsInput{}
+	}
+	output = &GetBucketTagsOutput{}
+	req = m.ucRequest("GET", "GetBucketTags", "/bucketTagging?bucket="+url.QueryEscape(input.Bucket), nil, output)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return
+}
+
+// GetBucketTags 获取存储空间的标签
+func (m *BucketManager) GetBucketTags(input *GetBucketTagsInput) (*GetBucketTagsOutput, error) {
+	req, out := m.GetBucketTagsRequest(input)
+	return out, req.Send()
+}
+
+// GetBucketTagsWithContext 和GetBucketTags一样， 可以使用ctx取消请求
+func (m *BucketManager) GetBucketTagsWithContext(ctx context.Context, input *GetBucketTagsInput, opts ...request.Option) (*GetBucketTagsOutput, error) {
+	req, out := m.GetBucketTagsRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// DeleteBucketTagsInput 删除存储空间所有标签的输入参数
+type DeleteBucketTagsInput struct {
+	// 存储空间的名字
+	Bucket string
+}
+
+// Validate 检查输入的参数
+func (i *DeleteBucketTagsInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "DeleteBucketTagsInput"}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// DeleteBucketTagsRequest 生成一个删除存储空间所有标签的请求
+func (m *BucketManager) DeleteBucketTagsRequest(input *DeleteBucketTagsInput) *request.Request {
+	if input == nil {
+		input = &DeleteBucketTagsInput{}
+	}
+	req := m.ucRequest("DELETE", "DeleteBucketTags", "/bucketTagging?bucket="+url.QueryEscape(input.Bucket), nil, nil)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return req
+}
+
+// DeleteBucketTags 删除存储空间的所有标签
+func (m *BucketManager) DeleteBucketTags(input *DeleteBucketTagsInput) error {
+	return m.DeleteBucketTagsRequest(input).Send()
+}
+
+// DeleteBucketTagsWithContext 和DeleteBucketTags一样， 可以使用ctx取消请求
+func (m *BucketManager) DeleteBucketTagsWithContext(ctx context.Context, input *DeleteBucketTagsInput, opts ...request.Option) error {
+	return sendWithContext(ctx, m.DeleteBucketTagsRequest(input), opts...)
+}
+
+// GetBucketInfoInput 获取存储空间信息的输入参数
+type GetBucketInfoInput struct {
+	// 存储空间的名字
+	Bucket string
+}
+
+// Validate 检查输入的参数
+func (i *GetBucketInfoInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "GetBucketInfoInput"}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// GetBucketInfoOutput 存储空间的信息
+type GetBucketInfoOutput struct {
+	// 存储空间所在的区域
+	Region string `json:"region"`
+
+	// 1表示私有空间， 0表示公开空间
+	Private int `json:"private"`
+
+	// 1表示开启了原图保护
+	Protected int `json:"protected"`
+
+	// 1表示禁用了默认首页
+	NoIndexPage int `json:"no_index_page"`
+
+	// 下载时返回的Cache-Control的max-age, 单位为秒， 0表示使用默认值
+	MaxAge int `json:"max_age"`
+
+	// 镜像回源的源站地址
+	Source string `json:"source"`
+
+	// 镜像回源时使用的Host
+	Host string `json:"host"`
+
+	// Referer防盗链的模式， 0表示关闭， 1表示白名单， 2表示黑名单
+	AntiLeechMode int `json:"anti_leech_mode"`
+
+	// Referer白名单
+	ReferWhiteList []string `json:"refer_wl"`
+
+	// Referer黑名单
+	ReferBlackList []string `json:"refer_bl"`
+
+	// 是否允许空Referer访问
+	NoRefer bool `json:"no_refer"`
+
+	// 镜像回源的时候是否也检查Referer防盗链
+	SourceEnabled bool `json:"source_enabled"`
+
+	// 数据处理的样式分隔符
+	Separator string `json:"separator"`
+
+	// 数据处理的样式， 样式名到数据处理命令的映射
+	Styles map[string]string `json:"styles"`
+}
+
+// IsPrivate 返回存储空间是否是私有空间
+func (o *GetBucketInfoOutput) IsPrivate() bool {
+	return o.Private == 1
+}
+
+// GetBucketInfoRequest 生成一个获取存储空间信息的请求
+// 存储空间不存在的时候返回qerr.ErrStorageNotExist
+func (m *BucketManager) GetBucketInfoRequest(input *GetBucketInfoInput) (req *request.Request, output *GetBucketInfoOutput) {
+	if input == nil {
+		input = &GetBucketInfoInput{}
+	}
+	output = &GetBucketInfoOutput{}
+	req = m.ucRequest("POST", "GetBucketInfo", "/v2/bucketInfo?bucket="+url.QueryEscape(input.Bucket), nil, output)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return
+}
+
+// GetBucketInfo 获取存储空间的信息
+func (m *BucketManager) GetBucketInfo(input *GetBucketInfoInput) (*GetBucketInfoOutput, error) {
+	req, out := m.GetBucketInfoRequest(input)
+	return out, req.Send()
+}
+
+// GetBucketInfoWithContext 和GetBucketInfo一样， 可以使用ctx取消请求
+func (m *BucketManager) GetBucketInfoWithContext(ctx context.Context, input *GetBucketInfoInput, opts ...request.Option) (*GetBucketInfoOutput, error) {
+	req, out := m.GetBucketInfoRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
@@ -0,0 +1,558 @@
+package kodo
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultFSCacheTTL BucketFS元数据缓存默认的有效期
+	DefaultFSCacheTTL = time.Minute
+
+	// DefaultFSCacheSize BucketFS元数据缓存默认最多保存的条目数
+	DefaultFSCacheSize = 1024
+)
+
+// BucketFS 把存储空间中以Prefix开头的资源映射为只读的文件系统， 实现了fs.FS, fs.StatFS和fs.ReadDirFS
+// 资源名按照"/"分隔为目录， 目录本身不需要存在对应的资源
+// 列举目录使用RsfHost, 获取文件信息使用RsHost, 读取文件通过Domain或者存储区域的IoHost做Range下载
+//
+//	fsys := kodo.NewBucketFS(svc, "bucket", "static/", func(f *kodo.BucketFS) {
+//		f.CacheTTL = 5 * time.Minute
+//	})
+//	tmpl, err := template.ParseFS(fsys, "templates/*.html")
+//	http.Handle("/", http.FileServer(fsys.HTTPFileSystem()))
+type BucketFS struct {
+	// 存储空间的名字
+	Bucket string
+
+	// 资源名的前缀， 文件名是资源名去掉Prefix之后的部分， 不为空的时候应该以"/"结尾
+	Prefix string
+
+	// 下载文件使用的域名， 为空的时候通过存储区域的IoHost下载
+	Domain string
+
+	// 通过Domain下载的时候是否使用私有下载地址
+	Private bool
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+
+	// 文件信息和目录列表的缓存有效期， 小于等于0的时候不缓存
+	CacheTTL time.Duration
+
+	// 缓存最多保存的条目数， 超过后淘汰最久没有使用的条目
+	CacheSize int
+
+	m *BucketManager
+
+	once  sync.Once
+	cache *fsCache
+}
+
+// NewBucketFS 返回一个BucketFS指针， options可以用来修改BucketFS的默认配置
+func NewBucketFS(svc *Kodo, bucket, prefix string, options ...func(*BucketFS)) *BucketFS {
+	f := &BucketFS{
+		Bucket:    bucket,
+		Prefix:    prefix,
+		CacheTTL:  DefaultFSCacheTTL,
+		CacheSize: DefaultFSCacheSize,
+		m:         NewBucketManager(svc),
+	}
+	for _, option := range options {
+		option(f)
+	}
+	return f
+}
+
+// HTTPFileSystem 返回BucketFS对应的http.FileSystem, 可以直接用于http.FileServer
+func (f *BucketFS) HTTPFileSystem() http.FileSystem {
+	return http.FS(f)
+}
+
+// ClearCache 清空元数据缓存， 存储空间中的资源发生变化之后可以调用
+func (f *BucketFS) ClearCache() {
+	f.getCache().clear()
+}
+
+func (f *BucketFS) getCache() *fsCache {
+	f.once.Do(func() {
+		f.cache = newFSCache(f.CacheTTL, f.CacheSize)
+	})
+	return f.cache
+}
+
+// key 返回文件名对应的资源名
+func (f *BucketFS) key(name string) string {
+	if name == "." {
+		return f.Prefix
+	}
+	return f.Prefix + name
+}
+
+// dirPrefix 返回列举目录name的时候使用的前缀
+func (f *BucketFS) dirPrefix(name string) string {
+	if name == "." {
+		return f.Prefix
+	}
+	return f.Prefix + name + "/"
+}
+
+// Open 实现了fs.FS接口， 返回的文件实现了io.Seeker和io.ReaderAt, 目录实现了fs.ReadDirFile
+func (f *BucketFS) Open(name string) (fs.File, error) {
+	info, err := f.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &bucketDir{fsys: f, name: name, info: info}, nil
+	}
+	return &bucketFile{fsys: f, info: info}, nil
+}
+
+// Stat 实现了fs.StatFS接口
+func (f *BucketFS) Stat(name string) (fs.FileInfo, error) {
+	info, err := f.stat("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (f *BucketFS) stat(op, name string) (*fileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &fileInfo{name: ".", dir: true}, nil
+	}
+
+	cache := f.getCache()
+	if v, ok := cache.get("stat:" + name); ok {
+		if v == nil {
+			return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		return v.(*fileInfo), nil
+	}
+
+	out, err := f.m.Stat(&StatInput{Entry: Entry{Bucket: f.Bucket, Key: f.key(name)}, Region: f.Region})
+	if err == nil {
+		info := newFileInfo(path.Base(name), &ListItem{
+			Key:      f.key(name),
+			Hash:     out.Hash,
+			Fsize:    out.Fsize,
+			MimeType: out.MimeType,
+			PutTime:  out.PutTime,
+			Type:     out.Type,
+			Status:   out.Status,
+			MD5:      out.MD5,
+		})
+		cache.put("stat:"+name, info)
+		return info, nil
+	}
+	if !isNotExistErr(err) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+
+	// 没有同名的资源， 有以name/开头的资源的时候name是一个目录
+	page, err := f.m.ListObjects(&ListObjectsInput{Bucket: f.Bucket, Prefix: f.dirPrefix(name), Limit: 1, Region: f.Region})
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if len(page.Items) == 0 && len(page.CommonPrefixes) == 0 {
+		cache.put("stat:"+name, nil)
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	info := &fileInfo{name: path.Base(name), dir: true}
+	cache.put("stat:"+name, info)
+	return info, nil
+}
+
+// ReadDir 实现了fs.ReadDirFS接口， 返回的目录项按照文件名排序
+func (f *BucketFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	infos, err := f.readDir(name)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]fs.DirEntry, len(infos))
+	for i, info := range infos {
+		entries[i] = info
+	}
+	return entries, nil
+}
+
+func (f *BucketFS) readDir(name string) ([]*fileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	cache := f.getCache()
+	if v, ok := cache.get("dir:" + name); ok {
+		return v.([]*fileInfo), nil
+	}
+
+	prefix := f.dirPrefix(name)
+	var infos []*fileInfo
+	err := f.m.ListObjectsPages(&ListObjectsInput{
+		Bucket:    f.Bucket,
+		Prefix:    prefix,
+		Delimiter: "/",
+		Region:    f.Region,
+	}, func(page *ListObjectsOutput, lastPage bool) bool {
+		for _, p := range page.CommonPrefixes {
+			infos = append(infos, &fileInfo{name: strings.TrimSuffix(strings.TrimPrefix(p, prefix), "/"), dir: true})
+		}
+		for i := range page.Items {
+			item := page.Items[i]
+			base := strings.TrimPrefix(item.Key, prefix)
+			// 以"/"结尾的资源一般是用来表示目录的占位资源
+			if base == "" {
+				continue
+			}
+			info := newFileInfo(base, &item)
+			infos = append(infos, info)
+			cache.put("stat:"+path.Join(name, base), info)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	if len(infos) == 0 && name != "." {
+		info, err := f.stat("readdir", name)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].name < infos[j].name
+	})
+	cache.put("dir:"+name, infos)
+	return infos, nil
+}
+
+// fileInfo 实现了fs.FileInfo和fs.DirEntry接口
+type fileInfo struct {
+	name string
+	dir  bool
+	item *ListItem
+}
+
+func newFileInfo(name string, item *ListItem) *fileInfo {
+	return &fileInfo{name: name, item: item}
+}
+
+func (i *fileInfo) Name() string { return i.name }
+func (i *fileInfo) IsDir() bool  { return i.dir }
+
+func (i *fileInfo) Size() int64 {
+	if i.item == nil {
+		return 0
+	}
+	return i.item.Fsize
+}
+
+func (i *fileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// ModTime 返回资源的上传时间， 目录的修改时间为零值
+func (i *fileInfo) ModTime() time.Time {
+	if i.item == nil {
+		return time.Time{}
+	}
+	return time.Unix(0, i.item.PutTime*100)
+}
+
+// Sys 返回文件对应的*ListItem, 目录返回nil
+func (i *fileInfo) Sys() interface{} {
+	if i.item == nil {
+		return nil
+	}
+	return i.item
+}
+
+func (i *fileInfo) Type() fs.FileMode          { return i.Mode().Type() }
+func (i *fileInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+// bucketFile BucketFS中打开的文件
+// 顺序读取的时候使用一个从当前位置到文件末尾的Range下载流， Seek之后重新开始下载
+type bucketFile struct {
+	fsys *BucketFS
+	info *fileInfo
+
+	off    int64
+	body   *io.PipeReader
+	cancel context.CancelFunc
+	closed bool
+}
+
+func (f *bucketFile) object(rng string) *GetObjectInput {
+	return &GetObjectInput{
+		Bucket:  f.fsys.Bucket,
+		Key:     f.info.item.Key,
+		Domain:  f.fsys.Domain,
+		Private: f.fsys.Private,
+		Range:   rng,
+		Region:  f.fsys.Region,
+	}
+}
+
+func (f *bucketFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *bucketFile) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, &fs.PathError{Op: "read", Path: f.info.name, Err: fs.ErrClosed}
+	}
+	if f.off >= f.info.Size() {
+		return 0, io.EOF
+	}
+	if f.body == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		pr, pw := io.Pipe()
+		input := f.object(fmt.Sprintf("bytes=%d-", f.off))
+		go func() {
+			_, err := f.fsys.m.GetObjectWithContext(ctx, input, pw)
+			pw.CloseWithError(err)
+		}()
+		f.body, f.cancel = pr, cancel
+	}
+	n, err := f.body.Read(p)
+	f.off += int64(n)
+	if err == io.EOF && f.off < f.info.Size() {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil && err != io.EOF {
+		f.stop()
+		return n, &fs.PathError{Op: "read", Path: f.info.name, Err: err}
+	}
+	return n, err
+}
+
+// ReadAt 实现了io.ReaderAt接口， 每次调用都是一个独立的Range下载， 不影响Read的位置
+func (f *bucketFile) ReadAt(p []byte, off int64) (int, error) {
+	if f.closed {
+		return 0, &fs.PathError{Op: "read", Path: f.info.name, Err: fs.ErrClosed}
+	}
+	if off < 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.info.name, Err: fs.ErrInvalid}
+	}
+	if off >= f.info.Size() {
+		return 0, io.EOF
+	}
+	end := off + int64(len(p))
+	if end > f.info.Size() {
+		end = f.info.Size()
+	}
+	if end == off {
+		return 0, nil
+	}
+	w := &sliceWriter{buf: p[:end-off]}
+	_, err := f.fsys.m.GetObject(f.object(fmt.Sprintf("bytes=%d-%d", off, end-1)), w)
+	if err == nil && w.n < len(w.buf) {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return w.n, &fs.PathError{Op: "read", Path: f.info.name, Err: err}
+	}
+	if w.n < len(p) {
+		return w.n, io.EOF
+	}
+	return w.n, nil
+}
+
+// Seek 实现了io.Seeker接口
+func (f *bucketFile) Seek(offset int64, whence int) (int64, error) {
+	if f.closed {
+		return 0, &fs.PathError{Op: "seek", Path: f.info.name, Err: fs.ErrClosed}
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.off
+	case io.SeekEnd:
+		offset += f.info.Size()
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.info.name, Err: fs.ErrInvalid}
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.info.name, Err: fs.ErrInvalid}
+	}
+	if offset != f.off {
+		f.stop()
+		f.off = offset
+	}
+	return offset, nil
+}
+
+func (f *bucketFile) Close() error {
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.info.name, Err: fs.ErrClosed}
+	}
+	f.stop()
+	f.closed = true
+	return nil
+}
+
+// stop 停止正在进行的下载
+func (f *bucketFile) stop() {
+	if f.body == nil {
+		return
+	}
+	f.cancel()
+	f.body.Close()
+	f.body, f.cancel = nil, nil
+}
+
+// sliceWriter 把数据写入固定长度的buf, 写满之后返回io.ErrShortWrite
+type sliceWriter struct {
+	buf []byte
+	n   int
+}
+
+func (w *sliceWriter) Write(p []byte) (int, error) {
+	n := copy(w.buf[w.n:], p)
+	w.n += n
+	if n < len(p) {
+		return n, io.ErrShortWrite
+	}
+	return n, nil
+}
+
+// bucketDir BucketFS中打开的目录， 实现了fs.ReadDirFile
+type bucketDir struct {
+	fsys *BucketFS
+	name string
+	info *fileInfo
+
+	entries []*fileInfo
+	loaded  bool
+	off     int
+}
+
+func (d *bucketDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *bucketDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: fs.ErrInvalid}
+}
+
+func (d *bucketDir) Close() error {
+	return nil
+}
+
+// ReadDir 实现了fs.ReadDirFile接口
+func (d *bucketDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.loaded {
+		entries, err := d.fsys.readDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries, d.loaded = entries, true
+	}
+	remain := len(d.entries) - d.off
+	if n > 0 && remain == 0 {
+		return nil, io.EOF
+	}
+	if n > 0 && n < remain {
+		remain = n
+	}
+	list := make([]fs.DirEntry, remain)
+	for i := range list {
+		list[i] = d.entries[d.off+i]
+	}
+	d.off += remain
+	return list, nil
+}
+
+// fsCache 带有效期的LRU缓存， 用于缓存文件信息和目录列表
+// value为nil的条目表示文件不存在
+type fsCache struct {
+	ttl  time.Duration
+	size int
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type fsCacheEntry struct {
+	key      string
+	value    interface{}
+	expireAt time.Time
+}
+
+func newFSCache(ttl time.Duration, size int) *fsCache {
+	if size <= 0 {
+		size = DefaultFSCacheSize
+	}
+	return &fsCache{ttl: ttl, size: size, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (c *fsCache) get(key string) (interface{}, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := e.Value.(*fsCacheEntry)
+	if time.Now().After(entry.expireAt) {
+		c.ll.Remove(e)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return entry.value, true
+}
+
+func (c *fsCache) put(key string, value interface{}) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expireAt := time.Now().Add(c.ttl)
+	if e, ok := c.items[key]; ok {
+		entry := e.Value.(*fsCacheEntry)
+		entry.value, entry.expireAt = value, expireAt
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&fsCacheEntry{key: key, value: value, expireAt: expireAt})
+	for c.ll.Len() > c.size {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.items, e.Value.(*fsCacheEntry).key)
+	}
+}
+
+func (c *fsCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+}
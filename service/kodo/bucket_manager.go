@@ -0,0 +1,513 @@
+package kodo
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/credentials"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/defs"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+// StorageClass 文件的存储类型
+type StorageClass int
+
+const (
+	// StorageStandard 标准存储
+	StorageStandard StorageClass = iota
+
+	// StorageIA 低频存储
+	StorageIA
+
+	// StorageArchive 归档存储
+	StorageArchive
+
+	// StorageDeepArchive 深度归档存储
+	StorageDeepArchive
+)
+
+// BucketManager 资源管理的客户端， 接口大多在RsHost上， 抓取和镜像预取在IoHost上， 异步抓取在APIHost上
+// 资源的状态码612(资源不存在), 614(目标资源已存在), 631(空间不存在)
+// 分别对应qerr.ErrResourceNotExist, qerr.ErrResourceExist, qerr.ErrStorageNotExist错误码
+// 多个操作可以通过Batch一次发送
+type BucketManager struct {
+	*Kodo
+}
+
+// NewBucketManager 返回一个BucketManager指针
+func NewBucketManager(svc *Kodo) *BucketManager {
+	return &BucketManager{Kodo: svc}
+}
+
+// Entry 代表存储空间中的一个资源
+type Entry struct {
+	Bucket string
+	Key    string
+}
+
+// Encoded 返回URL Safe Base64编码的Entry
+func (e Entry) Encoded() string {
+	return qiniu.EncodedEntry(e.Bucket, e.Key)
+}
+
+func (e Entry) bucketName() string {
+	return e.Bucket
+}
+
+func (e Entry) validate(invalidParams *request.ErrInvalidParams, prefix string) {
+	if e.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired(prefix + "Bucket"))
+	}
+	if e.Key == "" {
+		invalidParams.Add(request.NewErrParamRequired(prefix + "Key"))
+	}
+}
+
+// rsOperation 资源管理的操作， 可以单独发送， 也可以放到批量操作中
+type rsOperation interface {
+	request.Validator
+
+	// opPath 返回操作的路径， 比如/stat/<EncodedEntry>
+	opPath() string
+
+	// bucketName 返回操作的存储空间， 用于查询存储空间所在的区域
+	bucketName() string
+}
+
+func validateEntry(ctx string, e Entry) error {
+	invalidParams := request.ErrInvalidParams{Context: ctx}
+	e.validate(&invalidParams, "")
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// rsRequest 生成一个资源管理的请求
+func (m *BucketManager) rsRequest(method, apiName, region string, input rsOperation, data interface{}) *request.Request {
+	op := &request.API{
+		Method:      method,
+		Path:        input.opPath(),
+		ContentType: defs.CONTENT_TYPE_FORM,
+		TokenType:   credentials.TokenQBox,
+		ServiceName: ServiceName,
+		APIName:     apiName,
+	}
+	req := m.newRequest(op, nil, data)
+	m.setHosts(req, target{Region: region, Bucket: input.bucketName()}, oneHost(m.rsHost))
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return req
+}
+
+func sendWithContext(ctx context.Context, req *request.Request, opts ...request.Option) error {
+	req.SetContext(ctx)
+	req.ApplyOptions(opts...)
+	return req.Send()
+}
+
+// StatInput 获取资源元信息的输入参数
+type StatInput struct {
+	Entry
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+}
+
+// Validate 检查输入的参数
+func (i *StatInput) Validate() error {
+	return validateEntry("StatInput", i.Entry)
+}
+
+func (i *StatInput) opPath() string {
+	return "/stat/" + i.Encoded()
+}
+
+// StatOutput 资源的元信息
+type StatOutput struct {
+	// 文件大小， 单位Byte
+	Fsize int64 `json:"fsize"`
+
+	// 文件的Hash值
+	Hash string `json:"hash"`
+
+	// 文件的MimeType
+	MimeType string `json:"mimeType"`
+
+	// 上传时间， 单位为100纳秒
+	PutTime int64 `json:"putTime"`
+
+	// 存储类型
+	Type StorageClass `json:"type"`
+
+	// 文件状态， 0表示启用， 1表示禁用
+	Status int `json:"status"`
+
+	// 文件的MD5值
+	MD5 string `json:"md5,omitempty"`
+
+	// 文件的过期删除时间， Unix时间戳
+	Expiration int64 `json:"expiration,omitempty"`
+
+	// 归档存储文件的解冻状态， 1表示解冻中， 2表示解冻完成
+	RestoreStatus int `json:"restoreStatus,omitempty"`
+
+	// 自定义的元数据
+	Metadata map[string]string `json:"x-qn-meta,omitempty"`
+}
+
+// StatRequest 生成一个获取资源元信息的请求
+func (m *BucketManager) StatRequest(input *StatInput) (req *request.Request, output *StatOutput) {
+	if input == nil {
+		input = &StatInput{}
+	}
+	output = &StatOutput{}
+	req = m.rsRequest("GET", "Stat", input.Region, input, output)
+	return
+}
+
+// Stat 获取资源的元信息
+func (m *BucketManager) Stat(input *StatInput) (*StatOutput, error) {
+	req, out := m.StatRequest(input)
+	return out, req.Send()
+}
+
+// StatWithContext 和Stat一样， 可以使用ctx取消请求
+func (m *BucketManager) StatWithContext(ctx context.Context, input *StatInput, opts ...request.Option) (*StatOutput, error) {
+	req, out := m.StatRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// DeleteInput 删除资源的输入参数
+type DeleteInput struct {
+	Entry
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+}
+
+// Validate 检查输入的参数
+func (i *DeleteInput) Validate() error {
+	return validateEntry("DeleteInput", i.Entry)
+}
+
+func (i *DeleteInput) opPath() string {
+	return "/delete/" + i.Encoded()
+}
+
+// DeleteRequest 生成一个删除资源的请求
+func (m *BucketManager) DeleteRequest(input *DeleteInput) *request.Request {
+	if input == nil {
+		input = &DeleteInput{}
+	}
+	return m.rsRequest("POST", "Delete", input.Region, input, nil)
+}
+
+// Delete 删除资源
+func (m *BucketManager) Delete(input *DeleteInput) error {
+	return m.DeleteRequest(input).Send()
+}
+
+// DeleteWithContext 和Delete一样， 可以使用ctx取消请求
+func (m *BucketManager) DeleteWithContext(ctx context.Context, input *DeleteInput, opts ...request.Option) error {
+	return sendWithContext(ctx, m.DeleteRequest(input), opts...)
+}
+
+// CopyInput 复制资源的输入参数
+type CopyInput struct {
+	// 源资源
+	Src Entry
+
+	// 目标资源
+	Dest Entry
+
+	// 为true的时候， 如果目标资源已经存在会被覆盖， 否则返回qerr.ErrResourceExist错误
+	Force bool
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+}
+
+func (i *CopyInput) validate(ctx string) error {
+	invalidParams := request.ErrInvalidParams{Context: ctx}
+	i.Src.validate(&invalidParams, "Src.")
+	i.Dest.validate(&invalidParams, "Dest.")
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// Validate 检查输入的参数
+func (i *CopyInput) Validate() error {
+	return i.validate("CopyInput")
+}
+
+func (i *CopyInput) bucketName() string {
+	return i.Src.Bucket
+}
+
+func (i *CopyInput) opPath() string {
+	return fmt.Sprintf("/copy/%s/%s/force/%t", i.Src.Encoded(), i.Dest.Encoded(), i.Force)
+}
+
+// CopyRequest 生成一个复制资源的请求
+func (m *BucketManager) CopyRequest(input *CopyInput) *request.Request {
+	if input == nil {
+		input = &CopyInput{}
+	}
+	return m.rsRequest("POST", "Copy", input.Region, input, nil)
+}
+
+// Copy 复制资源， 源资源和目标资源必须在同一个区域
+func (m *BucketManager) Copy(input *CopyInput) error {
+	return m.CopyRequest(input).Send()
+}
+
+// CopyWithContext 和Copy一样， 可以使用ctx取消请求
+func (m *BucketManager) CopyWithContext(ctx context.Context, input *CopyInput, opts ...request.Option) error {
+	return sendWithContext(ctx, m.CopyRequest(input), opts...)
+}
+
+// MoveInput 移动(重命名)资源的输入参数
+type MoveInput CopyInput
+
+// Validate 检查输入的参数
+func (i *MoveInput) Validate() error {
+	return (*CopyInput)(i).validate("MoveInput")
+}
+
+func (i *MoveInput) bucketName() string {
+	return i.Src.Bucket
+}
+
+func (i *MoveInput) opPath() string {
+	return fmt.Sprintf("/move/%s/%s/force/%t", i.Src.Encoded(), i.Dest.Encoded(), i.Force)
+}
+
+// MoveRequest 生成一个移动资源的请求
+func (m *BucketManager) MoveRequest(input *MoveInput) *request.Request {
+	if input == nil {
+		input = &MoveInput{}
+	}
+	return m.rsRequest("POST", "Move", input.Region, input, nil)
+}
+
+// Move 移动或者重命名资源， 源资源和目标资源必须在同一个区域
+func (m *BucketManager) Move(input *MoveInput) error {
+	return m.MoveRequest(input).Send()
+}
+
+// MoveWithContext 和Move一样， 可以使用ctx取消请求
+func (m *BucketManager) MoveWithContext(ctx context.Context, input *MoveInput, opts ...request.Option) error {
+	return sendWithContext(ctx, m.MoveRequest(input), opts...)
+}
+
+// ChangeMimeInput 修改资源MimeType和元数据的输入参数
+type ChangeMimeInput struct {
+	Entry
+
+	// 新的MimeType, 为空的时候不修改
+	MimeType string
+
+	// 要修改的自定义元数据， key必须以"x-qn-meta-"开头
+	Metadata map[string]string
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+}
+
+// Validate 检查输入的参数
+func (i *ChangeMimeInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "ChangeMimeInput"}
+	i.validate(&invalidParams, "")
+	if i.MimeType == "" && len(i.Metadata) == 0 {
+		invalidParams.Add(request.NewErrParamRequired("MimeType"))
+	}
+	for k := range i.Metadata {
+		if !strings.HasPrefix(k, "x-qn-meta-") {
+			invalidParams.Add(request.NewErrParamFormat("Metadata", "x-qn-meta-<name>", k))
+		}
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+func (i *ChangeMimeInput) opPath() string {
+	path := "/chgm/" + i.Encoded()
+	if i.MimeType != "" {
+		path += "/mime/" + base64.URLEncoding.EncodeToString([]byte(i.MimeType))
+	}
+	keys := make([]string, 0, len(i.Metadata))
+	for k := range i.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		path += "/" + k + "/" + base64.URLEncoding.EncodeToString([]byte(i.Metadata[k]))
+	}
+	return path
+}
+
+// ChangeMimeRequest 生成一个修改资源MimeType和元数据的请求
+func (m *BucketManager) ChangeMimeRequest(input *ChangeMimeInput) *request.Request {
+	if input == nil {
+		input = &ChangeMimeInput{}
+	}
+	return m.rsRequest("POST", "ChangeMime", input.Region, input, nil)
+}
+
+// ChangeMime 修改资源的MimeType和自定义元数据
+func (m *BucketManager) ChangeMime(input *ChangeMimeInput) error {
+	return m.ChangeMimeRequest(input).Send()
+}
+
+// ChangeMimeWithContext 和ChangeMime一样， 可以使用ctx取消请求
+func (m *BucketManager) ChangeMimeWithContext(ctx context.Context, input *ChangeMimeInput, opts ...request.Option) error {
+	return sendWithContext(ctx, m.ChangeMimeRequest(input), opts...)
+}
+
+// ChangeTypeInput 修改资源存储类型的输入参数
+type ChangeTypeInput struct {
+	Entry
+
+	// 新的存储类型
+	Type StorageClass
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+}
+
+// Validate 检查输入的参数
+func (i *ChangeTypeInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "ChangeTypeInput"}
+	i.validate(&invalidParams, "")
+	if i.Type < StorageStandard || i.Type > StorageDeepArchive {
+		invalidParams.Add(request.NewErrParamFormat("Type", "0, 1, 2 or 3", fmt.Sprint(i.Type)))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+func (i *ChangeTypeInput) opPath() string {
+	return fmt.Sprintf("/chtype/%s/type/%d", i.Encoded(), i.Type)
+}
+
+// ChangeTypeRequest 生成一个修改资源存储类型的请求
+func (m *BucketManager) ChangeTypeRequest(input *ChangeTypeInput) *request.Request {
+	if input == nil {
+		input = &ChangeTypeInput{}
+	}
+	return m.rsRequest("POST", "ChangeType", input.Region, input, nil)
+}
+
+// ChangeType 修改资源的存储类型
+func (m *BucketManager) ChangeType(input *ChangeTypeInput) error {
+	return m.ChangeTypeRequest(input).Send()
+}
+
+// ChangeTypeWithContext 和ChangeType一样， 可以使用ctx取消请求
+func (m *BucketManager) ChangeTypeWithContext(ctx context.Context, input *ChangeTypeInput, opts ...request.Option) error {
+	return sendWithContext(ctx, m.ChangeTypeRequest(input), opts...)
+}
+
+// DeleteAfterDaysInput 设置资源生命周期的输入参数
+type DeleteAfterDaysInput struct {
+	Entry
+
+	// 文件在多少天后被删除， 0表示取消生命周期设置
+	Days int
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+}
+
+// Validate 检查输入的参数
+func (i *DeleteAfterDaysInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "DeleteAfterDaysInput"}
+	i.validate(&invalidParams, "")
+	if i.Days < 0 {
+		invalidParams.Add(request.NewErrParamMinValue("Days", 0))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+func (i *DeleteAfterDaysInput) opPath() string {
+	return fmt.Sprintf("/deleteAfterDays/%s/%d", i.Encoded(), i.Days)
+}
+
+// DeleteAfterDaysRequest 生成一个设置资源生命周期的请求
+func (m *BucketManager) DeleteAfterDaysRequest(input *DeleteAfterDaysInput) *request.Request {
+	if input == nil {
+		input = &DeleteAfterDaysInput{}
+	}
+	return m.rsRequest("POST", "DeleteAfterDays", input.Region, input, nil)
+}
+
+// DeleteAfterDays 设置资源在多少天后被删除
+func (m *BucketManager) DeleteAfterDays(input *DeleteAfterDaysInput) error {
+	return m.DeleteAfterDaysRequest(input).Send()
+}
+
+// DeleteAfterDaysWithContext 和DeleteAfterDays一样， 可以使用ctx取消请求
+func (m *BucketManager) DeleteAfterDaysWithContext(ctx context.Context, input *DeleteAfterDaysInput, opts ...request.Option) error {
+	return sendWithContext(ctx, m.DeleteAfterDaysRequest(input), opts...)
+}
+
+// RestoreArInput 解冻归档存储资源的输入参数
+type RestoreArInput struct {
+	Entry
+
+	// 解冻后的有效期， 单位为天， 范围为1~7
+	FreezeAfterDays int
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+}
+
+// Validate 检查输入的参数
+func (i *RestoreArInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "RestoreArInput"}
+	i.validate(&invalidParams, "")
+	if i.FreezeAfterDays < 1 || i.FreezeAfterDays > 7 {
+		invalidParams.Add(request.NewErrParamFormat("FreezeAfterDays", "1~7", fmt.Sprint(i.FreezeAfterDays)))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+func (i *RestoreArInput) opPath() string {
+	return fmt.Sprintf("/restoreAr/%s/freezeAfterDays/%d", i.Encoded(), i.FreezeAfterDays)
+}
+
+// RestoreArRequest 生成一个解冻归档存储资源的请求
+func (m *BucketManager) RestoreArRequest(input *RestoreArInput) *request.Request {
+	if input == nil {
+		input = &RestoreArInput{}
+	}
+	return m.rsRequest("POST", "RestoreAr", input.Region, input, nil)
+}
+
+// RestoreAr 解冻归档存储的资源， 解冻完成后才可以下载
+func (m *BucketManager) RestoreAr(input *RestoreArInput) error {
+	return m.RestoreArRequest(input).Send()
+}
+
+// RestoreArWithContext 和RestoreAr一样， 可以使用ctx取消请求
+func (m *BucketManager) RestoreArWithContext(ctx context.Context, input *RestoreArInput, opts ...request.Option) error {
+	return sendWithContext(ctx, m.RestoreArRequest(input), opts...)
+}
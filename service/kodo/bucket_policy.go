@@ -0,0 +1,789 @@
+package kodo
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+// ErrorPageKey 静态网站的404页面对应的资源名， 存储空间中存在该资源的时候， 访问不存在的资源会返回该资源的内容
+const ErrorPageKey = "errno-404"
+
+// RefererMode Referer防盗链的模式
+type RefererMode int
+
+// Referer防盗链的模式
+const (
+	// RefererOff 关闭Referer防盗链
+	RefererOff RefererMode = 0
+
+	// RefererWhiteList 只允许Patterns中的Referer访问
+	RefererWhiteList RefererMode = 1
+
+	// RefererBlackList 禁止Patterns中的Referer访问
+	RefererBlackList RefererMode = 2
+)
+
+// EventType 事件通知的事件类型
+type EventType string
+
+// 事件通知的事件类型
+const (
+	EventPut                EventType = "put"
+	EventMkfile             EventType = "mkfile"
+	EventDelete             EventType = "delete"
+	EventCopy               EventType = "copy"
+	EventMove               EventType = "move"
+	EventAppend             EventType = "append"
+	EventDisable            EventType = "disable"
+	EventEnable             EventType = "enable"
+	EventDeleteMarkerCreate EventType = "deleteMarkerCreate"
+)
+
+// MirrorSource 镜像回源的配置， 访问的资源不存在的时候从源站获取并保存到存储空间中
+type MirrorSource struct {
+	// 源站地址， 比如"https://example.com", 为空表示没有设置镜像回源
+	Source string `json:"source"`
+
+	// 回源请求使用的Host, 为空的时候使用源站地址中的域名
+	Host string `json:"host,omitempty"`
+}
+
+// RefererAntiLeech Referer防盗链的配置
+type RefererAntiLeech struct {
+	// 防盗链的模式
+	Mode RefererMode `json:"mode"`
+
+	// 白名单或者黑名单中的Referer, 支持"*"开头的通配符， 比如"*.example.com"
+	Patterns []string `json:"patterns,omitempty"`
+
+	// 是否允许空Referer访问
+	AllowEmptyReferer bool `json:"allowEmptyReferer"`
+
+	// 镜像回源的时候是否也检查Referer
+	SourceEnabled bool `json:"sourceEnabled"`
+}
+
+// CORSRule 跨域规则
+type CORSRule struct {
+	// 允许的来源， 比如"https://example.com", "*"表示所有来源
+	AllowedOrigins []string `json:"allowed_origin"`
+
+	// 允许的方法， 比如"GET", "PUT"
+	AllowedMethods []string `json:"allowed_method"`
+
+	// 允许的请求头， "*"表示所有请求头
+	AllowedHeaders []string `json:"allowed_header,omitempty"`
+
+	// 允许浏览器访问的响应头
+	ExposedHeaders []string `json:"exposed_header,omitempty"`
+
+	// 预检请求的缓存时间， 单位为秒
+	MaxAge int64 `json:"max_age,omitempty"`
+}
+
+// StaticWebsite 静态网站的配置
+// 404页面是存储空间中名为ErrorPageKey的资源， 不需要单独设置
+type StaticWebsite struct {
+	// 是否开启默认首页， 开启后访问目录的时候返回目录下的index.html
+	IndexPage bool `json:"indexPage"`
+}
+
+// EventRule 事件通知规则， 资源名匹配Prefix和Suffix的资源发生Events中的事件时回调CallbackURLs
+type EventRule struct {
+	// 规则的名字， 在存储空间中唯一
+	Name string `json:"name"`
+
+	// 匹配的资源名前缀
+	Prefix string `json:"prefix,omitempty"`
+
+	// 匹配的资源名后缀
+	Suffix string `json:"suffix,omitempty"`
+
+	// 通知的事件
+	Events []EventType `json:"event"`
+
+	// 回调地址， 多个地址的时候依次重试
+	CallbackURLs []string `json:"callback_urls"`
+
+	// 回调请求签名使用的AccessKey, 为空的时候不签名
+	AccessKey string `json:"access_key,omitempty"`
+
+	// 回调请求使用的Host
+	Host string `json:"host,omitempty"`
+}
+
+// validate 检查规则， field是规则在输入参数中的字段名
+func (e *EventRule) validate(field string, invalidParams *request.ErrInvalidParams) {
+	if e.Name == "" {
+		invalidParams.Add(request.NewErrParamRequired(field + ".Name"))
+	}
+	if len(e.Events) == 0 {
+		invalidParams.Add(request.NewErrParamRequired(field + ".Events"))
+	}
+	if len(e.CallbackURLs) == 0 {
+		invalidParams.Add(request.NewErrParamRequired(field + ".CallbackURLs"))
+	}
+}
+
+// query 返回添加或者更新规则的请求参数
+func (e *EventRule) query(bucket string) string {
+	v := make(url.Values)
+	v.Set("bucket", bucket)
+	v.Set("name", e.Name)
+	if e.Prefix != "" {
+		v.Set("prefix", e.Prefix)
+	}
+	if e.Suffix != "" {
+		v.Set("suffix", e.Suffix)
+	}
+	for _, event := range e.Events {
+		v.Add("event", string(event))
+	}
+	for _, u := range e.CallbackURLs {
+		v.Add("callbackURL", u)
+	}
+	if e.AccessKey != "" {
+		v.Set("access_key", e.AccessKey)
+	}
+	if e.Host != "" {
+		v.Set("host", e.Host)
+	}
+	return v.Encode()
+}
+
+// MirrorSource 返回镜像回源的配置， 没有设置的时候返回nil
+func (o *GetBucketInfoOutput) MirrorSource() *MirrorSource {
+	if o.Source == "" {
+		return nil
+	}
+	return &MirrorSource{Source: o.Source, Host: o.Host}
+}
+
+// RefererAntiLeech 返回Referer防盗链的配置
+func (o *GetBucketInfoOutput) RefererAntiLeech() *RefererAntiLeech {
+	r := &RefererAntiLeech{
+		Mode:              RefererMode(o.AntiLeechMode),
+		AllowEmptyReferer: o.NoRefer,
+		SourceEnabled:     o.SourceEnabled,
+	}
+	switch r.Mode {
+	case RefererWhiteList:
+		r.Patterns = o.ReferWhiteList
+	case RefererBlackList:
+		r.Patterns = o.ReferBlackList
+	}
+	return r
+}
+
+// StaticWebsite 返回静态网站的配置
+func (o *GetBucketInfoOutput) StaticWebsite() *StaticWebsite {
+	return &StaticWebsite{IndexPage: o.NoIndexPage == 0}
+}
+
+// SetMirrorSourceInput 设置镜像回源的输入参数
+type SetMirrorSourceInput struct {
+	// 存储空间的名字
+	Bucket string
+
+	// 镜像回源的配置， Source为空的时候取消镜像回源
+	MirrorSource
+}
+
+// Validate 检查输入的参数
+func (i *SetMirrorSourceInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "SetMirrorSourceInput"}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if i.Source != "" {
+		if u, err := url.Parse(i.Source); err != nil || u.Scheme == "" || u.Host == "" {
+			invalidParams.Add(request.NewErrParamFormat("Source", "http(s)://<host>", i.Source))
+		}
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// SetMirrorSourceRequest 生成一个设置镜像回源的请求
+func (m *BucketManager) SetMirrorSourceRequest(input *SetMirrorSourceInput) *request.Request {
+	if input == nil {
+		input = &SetMirrorSourceInput{}
+	}
+	path := "/unimage/" + url.PathEscape(input.Bucket)
+	if input.Source != "" {
+		path = fmt.Sprintf("/image/%s/from/%s", url.PathEscape(input.Bucket), base64.URLEncoding.EncodeToString([]byte(input.Source)))
+		if input.Host != "" {
+			path += "/host/" + base64.URLEncoding.EncodeToString([]byte(input.Host))
+		}
+	}
+	req := m.ucRequest("POST", "SetMirrorSource", path, nil, nil)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return req
+}
+
+// SetMirrorSource 设置或者取消镜像回源
+func (m *BucketManager) SetMirrorSource(input *SetMirrorSourceInput) error {
+	return m.SetMirrorSourceRequest(input).Send()
+}
+
+// SetMirrorSourceWithContext 和SetMirrorSource一样， 可以使用ctx取消请求
+func (m *BucketManager) SetMirrorSourceWithContext(ctx context.Context, input *SetMirrorSourceInput, opts ...request.Option) error {
+	return sendWithContext(ctx, m.SetMirrorSourceRequest(input), opts...)
+}
+
+// SetRefererAntiLeechInput 设置Referer防盗链的输入参数
+type SetRefererAntiLeechInput struct {
+	// 存储空间的名字
+	Bucket string
+
+	// Referer防盗链的配置
+	RefererAntiLeech
+}
+
+// Validate 检查输入的参数
+func (i *SetRefererAntiLeechInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "SetRefererAntiLeechInput"}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	switch i.Mode {
+	case RefererOff:
+	case RefererWhiteList, RefererBlackList:
+		if len(i.Patterns) == 0 {
+			invalidParams.Add(request.NewErrParamRequired("Patterns"))
+		}
+	default:
+		invalidParams.Add(request.NewErrParamFormat("Mode", "0, 1 or 2", strconv.Itoa(int(i.Mode))))
+	}
+	for n, p := range i.Patterns {
+		if p == "" || strings.Contains(p, ";") {
+			invalidParams.Add(request.NewErrParamFormat(fmt.Sprintf("Patterns[%d]", n), "non-empty without ';'", p))
+		}
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// SetRefererAntiLeechRequest 生成一个设置Referer防盗链的请求
+func (m *BucketManager) SetRefererAntiLeechRequest(input *SetRefererAntiLeechInput) *request.Request {
+	if input == nil {
+		input = &SetRefererAntiLeechInput{}
+	}
+	v := make(url.Values)
+	v.Set("bucket", input.Bucket)
+	v.Set("mode", strconv.Itoa(int(input.Mode)))
+	v.Set("norefer", boolString(input.AllowEmptyReferer))
+	v.Set("pattern", strings.Join(input.Patterns, ";"))
+	v.Set("source_enabled", boolString(input.SourceEnabled))
+	req := m.ucRequest("POST", "SetRefererAntiLeech", "/referAntiLeech?"+v.Encode(), nil, nil)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return req
+}
+
+// SetRefererAntiLeech 设置Referer防盗链
+func (m *BucketManager) SetRefererAntiLeech(input *SetRefererAntiLeechInput) error {
+	return m.SetRefererAntiLeechRequest(input).Send()
+}
+
+// SetRefererAntiLeechWithContext 和SetRefererAntiLeech一样， 可以使用ctx取消请求
+func (m *BucketManager) SetRefererAntiLeechWithContext(ctx context.Context, input *SetRefererAntiLeechInput, opts ...request.Option) error {
+	return sendWithContext(ctx, m.SetRefererAntiLeechRequest(input), opts...)
+}
+
+// GetCORSRulesInput 获取跨域规则的输入参数
+type GetCORSRulesInput struct {
+	// 存储空间的名字
+	Bucket string
+}
+
+// Validate 检查输入的参数
+func (i *GetCORSRulesInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "GetCORSRulesInput"}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// GetCORSRulesOutput 获取跨域规则的结果
+type GetCORSRulesOutput struct {
+	Rules []CORSRule
+}
+
+// GetCORSRulesRequest 生成一个获取跨域规则的请求
+func (m *BucketManager) GetCORSRulesRequest(input *GetCORSRulesInput) (req *request.Request, output *GetCORSRulesOutput) {
+	if input == nil {
+		input = &GetCORSRulesInput{}
+	}
+	output = &GetCORSRulesOutput{}
+	req = m.ucRequest("GET", "GetCORSRules", "/corsRules/get/"+url.PathEscape(input.Bucket), nil, &output.Rules)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return
+}
+
+// GetCORSRules 获取存储空间的跨域规则
+func (m *BucketManager) GetCORSRules(input *GetCORSRulesInput) (*GetCORSRulesOutput, error) {
+	req, out := m.GetCORSRulesRequest(input)
+	return out, req.Send()
+}
+
+// GetCORSRulesWithContext 和GetCORSRules一样， 可以使用ctx取消请求
+func (m *BucketManager) GetCORSRulesWithContext(ctx context.Context, input *GetCORSRulesInput, opts ...request.Option) (*GetCORSRulesOutput, error) {
+	req, out := m.GetCORSRulesRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// SetCORSRulesInput 设置跨域规则的输入参数
+type SetCORSRulesInput struct {
+	// 存储空间的名字
+	Bucket string
+
+	// 跨域规则， 会覆盖原有的所有规则， 为空的时候清除所有规则
+	Rules []CORSRule
+}
+
+// Validate 检查输入的参数
+func (i *SetCORSRulesInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "SetCORSRulesInput"}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	for n, r := range i.Rules {
+		field := fmt.Sprintf("Rules[%d]", n)
+		if len(r.AllowedOrigins) == 0 {
+			invalidParams.Add(request.NewErrParamRequired(field + ".AllowedOrigins"))
+		}
+		if len(r.AllowedMethods) == 0 {
+			invalidParams.Add(request.NewErrParamRequired(field + ".AllowedMethods"))
+		}
+		if r.MaxAge < 0 {
+			invalidParams.Add(request.NewErrParamMinValue(field+".MaxAge", 0))
+		}
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// SetCORSRulesRequest 生成一个设置跨域规则的请求
+func (m *BucketManager) SetCORSRulesRequest(input *SetCORSRulesInput) *request.Request {
+	if input == nil {
+		input = &SetCORSRulesInput{}
+	}
+	rules := input.Rules
+	if rules == nil {
+		rules = []CORSRule{}
+	}
+	req := m.ucRequest("POST", "SetCORSRules", "/corsRules/set/"+url.PathEscape(input.Bucket), &rules, nil)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return req
+}
+
+// SetCORSRules 设置存储空间的跨域规则
+func (m *BucketManager) SetCORSRules(input *SetCORSRulesInput) error {
+	return m.SetCORSRulesRequest(input).Send()
+}
+
+// SetCORSRulesWithContext 和SetCORSRules一样， 可以使用ctx取消请求
+func (m *BucketManager) SetCORSRulesWithContext(ctx context.Context, input *SetCORSRulesInput, opts ...request.Option) error {
+	return sendWithContext(ctx, m.SetCORSRulesRequest(input), opts...)
+}
+
+// SetStaticWebsiteInput 设置静态网站的输入参数
+type SetStaticWebsiteInput struct {
+	// 存储空间的名字
+	Bucket string
+
+	// 静态网站的配置
+	StaticWebsite
+}
+
+// Validate 检查输入的参数
+func (i *SetStaticWebsiteInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "SetStaticWebsiteInput"}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// SetStaticWebsiteRequest 生成一个设置静态网站的请求
+func (m *BucketManager) SetStaticWebsiteRequest(input *SetStaticWebsiteInput) *request.Request {
+	if input == nil {
+		input = &SetStaticWebsiteInput{}
+	}
+	v := make(url.Values)
+	v.Set("bucket", input.Bucket)
+	v.Set("noIndexPage", boolString(!input.IndexPage))
+	req := m.ucRequest("POST", "SetStaticWebsite", "/noIndexPage?"+v.Encode(), nil, nil)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return req
+}
+
+// SetStaticWebsite 设置静态网站的默认首页
+func (m *BucketManager) SetStaticWebsite(input *SetStaticWebsiteInput) error {
+	return m.SetStaticWebsiteRequest(input).Send()
+}
+
+// SetStaticWebsiteWithContext 和SetStaticWebsite一样， 可以使用ctx取消请求
+func (m *BucketManager) SetStaticWebsiteWithContext(ctx context.Context, input *SetStaticWebsiteInput, opts ...request.Option) error {
+	return sendWithContext(ctx, m.SetStaticWebsiteRequest(input), opts...)
+}
+
+// SetMaxAgeInput 设置下载响应的Cache-Control的输入参数
+type SetMaxAgeInput struct {
+	// 存储空间的名字
+	Bucket string
+
+	// Cache-Control的max-age, 单位为秒， 小于等于0的时候恢复默认值
+	MaxAge int
+}
+
+// Validate 检查输入的参数
+func (i *SetMaxAgeInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "SetMaxAgeInput"}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// SetMaxAgeRequest 生成一个设置下载响应的Cache-Control的请求
+func (m *BucketManager) SetMaxAgeRequest(input *SetMaxAgeInput) *request.Request {
+	if input == nil {
+		input = &SetMaxAgeInput{}
+	}
+	maxAge := input.MaxAge
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	v := make(url.Values)
+	v.Set("bucket", input.Bucket)
+	v.Set("maxAge", strconv.Itoa(maxAge))
+	req := m.ucRequest("POST", "SetMaxAge", "/maxAge?"+v.Encode(), nil, nil)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return req
+}
+
+// SetMaxAge 设置下载响应的Cache-Control的max-age
+func (m *BucketManager) SetMaxAge(input *SetMaxAgeInput) error {
+	return m.SetMaxAgeRequest(input).Send()
+}
+
+// SetMaxAgeWithContext 和SetMaxAge一样， 可以使用ctx取消请求
+func (m *BucketManager) SetMaxAgeWithContext(ctx context.Context, input *SetMaxAgeInput, opts ...request.Option) error {
+	return sendWithContext(ctx, m.SetMaxAgeRequest(input), opts...)
+}
+
+// ListEventRulesInput 列举事件通知规则的输入参数
+type ListEventRulesInput struct {
+	// 存储空间的名字
+	Bucket string
+}
+
+// Validate 检查输入的参数
+func (i *ListEventRulesInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "ListEventRulesInput"}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// ListEventRulesOutput 列举事件通知规则的结果
+type ListEventRulesOutput struct {
+	Rules []EventRule
+}
+
+// ListEventRulesRequest 生成一个列举事件通知规则的请求
+func (m *BucketManager) ListEventRulesRequest(input *ListEventRulesInput) (req *request.Request, output *ListEventRulesOutput) {
+	if input == nil {
+		input = &ListEventRulesInput{}
+	}
+	output = &ListEventRulesOutput{}
+	req = m.ucRequest("GET", "ListEventRules", "/events/get?bucket="+url.QueryEscape(input.Bucket), nil, &output.Rules)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return
+}
+
+// ListEventRules 列举存储空间的事件通知规则
+func (m *BucketManager) ListEventRules(input *ListEventRulesInput) (*ListEventRulesOutput, error) {
+	req, out := m.ListEventRulesRequest(input)
+	return out, req.Send()
+}
+
+// ListEventRulesWithContext 和ListEventRules一样， 可以使用ctx取消请求
+func (m *BucketManager) ListEventRulesWithContext(ctx context.Context, input *ListEventRulesInput, opts ...request.Option) (*ListEventRulesOutput, error) {
+	req, out := m.ListEventRulesRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// PutEventRuleInput 添加或者更新事件通知规则的输入参数
+type PutEventRuleInput struct {
+	// 存储空间的名字
+	Bucket string
+
+	// 事件通知规则
+	EventRule
+
+	// 为true的时候更新同名的规则， 否则添加新的规则
+	Update bool
+}
+
+// Validate 检查输入的参数
+func (i *PutEventRuleInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "PutEventRuleInput"}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	i.EventRule.validate("EventRule", &invalidParams)
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// PutEventRuleRequest 生成一个添加或者更新事件通知规则的请求
+func (m *BucketManager) PutEventRuleRequest(input *PutEventRuleInput) *request.Request {
+	if input == nil {
+		input = &PutEventRuleInput{}
+	}
+	path := "/events/add?"
+	if input.Update {
+		path = "/events/update?"
+	}
+	req := m.ucRequest("POST", "PutEventRule", path+input.query(input.Bucket), nil, nil)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return req
+}
+
+// PutEventRule 添加或者更新事件通知规则
+func (m *BucketManager) PutEventRule(input *PutEventRuleInput) error {
+	return m.PutEventRuleRequest(input).Send()
+}
+
+// PutEventRuleWithContext 和PutEventRule一样， 可以使用ctx取消请求
+func (m *BucketManager) PutEventRuleWithContext(ctx context.Context, input *PutEventRuleInput, opts ...request.Option) error {
+	return sendWithContext(ctx, m.PutEventRuleRequest(input), opts...)
+}
+
+// DeleteEventRuleInput 删除事件通知规则的输入参数
+type DeleteEventRuleInput struct {
+	// 存储空间的名字
+	Bucket string
+
+	// 规则的名字
+	Name string
+}
+
+// Validate 检查输入的参数
+func (i *DeleteEventRuleInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "DeleteEventRuleInput"}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if i.Name == "" {
+		invalidParams.Add(request.NewErrParamRequired("Name"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// DeleteEventRuleRequest 生成一个删除事件通知规则的请求
+func (m *BucketManager) DeleteEventRuleRequest(input *DeleteEventRuleInput) *request.Request {
+	if input == nil {
+		input = &DeleteEventRuleInput{}
+	}
+	v := make(url.Values)
+	v.Set("bucket", input.Bucket)
+	v.Set("name", input.Name)
+	req := m.ucRequest("POST", "DeleteEventRule", "/events/delete?"+v.Encode(), nil, nil)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return req
+}
+
+// DeleteEventRule 删除事件通知规则
+func (m *BucketManager) DeleteEventRule(input *DeleteEventRuleInput) error {
+	return m.DeleteEventRuleRequest(input).Send()
+}
+
+// DeleteEventRuleWithContext 和DeleteEventRule一样， 可以使用ctx取消请求
+func (m *BucketManager) DeleteEventRuleWithContext(ctx context.Context, input *DeleteEventRuleInput, opts ...request.Option) error {
+	return sendWithContext(ctx, m.DeleteEventRuleRequest(input), opts...)
+}
+
+// BucketPolicy 存储空间的完整配置， 可以序列化为JSON保存在代码仓库中， 用ApplyBucketPolicy应用到存储空间
+// 为nil的字段表示不管理该项配置， ApplyBucketPolicy不会修改
+//
+//	policy, err := m.GetBucketPolicy(ctx, "bucket")
+//	data, _ := json.MarshalIndent(policy, "", "  ")
+type BucketPolicy struct {
+	Mirror  *MirrorSource     `json:"mirror,omitempty"`
+	Referer *RefererAntiLeech `json:"referer,omitempty"`
+	Website *StaticWebsite    `json:"website,omitempty"`
+
+	// 下载响应的Cache-Control的max-age, 单位为秒， 0表示默认值
+	MaxAge *int `json:"maxAge,omitempty"`
+
+	// 为nil的时候不管理， 为空切片的时候清除所有跨域规则
+	CORS []CORSRule `json:"cors"`
+
+	// 为nil的时候不管理， 为空切片的时候删除所有事件通知规则
+	Events []EventRule `json:"events"`
+}
+
+// GetBucketPolicy 读取存储空间的完整配置， 返回的配置的所有字段都不为nil
+// 没有设置镜像回源的时候Mirror的Source为空
+func (m *BucketManager) GetBucketPolicy(ctx context.Context, bucket string, opts ...request.Option) (*BucketPolicy, error) {
+	info, err := m.GetBucketInfoWithContext(ctx, &GetBucketInfoInput{Bucket: bucket}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	cors, err := m.GetCORSRulesWithContext(ctx, &GetCORSRulesInput{Bucket: bucket}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	events, err := m.ListEventRulesWithContext(ctx, &ListEventRulesInput{Bucket: bucket}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &BucketPolicy{
+		Mirror:  &MirrorSource{Source: info.Source, Host: info.Host},
+		Referer: info.RefererAntiLeech(),
+		Website: info.StaticWebsite(),
+		MaxAge:  &info.MaxAge,
+		CORS:    cors.Rules,
+		Events:  events.Rules,
+	}
+	if policy.CORS == nil {
+		policy.CORS = []CORSRule{}
+	}
+	if policy.Events == nil {
+		policy.Events = []EventRule{}
+	}
+	sort.Slice(policy.Events, func(i, j int) bool {
+		return policy.Events[i].Name < policy.Events[j].Name
+	})
+	return policy, nil
+}
+
+// ApplyBucketPolicy 把配置应用到存储空间， 只修改policy中不为nil的部分
+// 事件通知规则按照名字对比， 多余的规则会被删除， 同名的规则会被更新， 遇到第一个错误的时候停止
+func (m *BucketManager) ApplyBucketPolicy(ctx context.Context, bucket string, policy *BucketPolicy, opts ...request.Option) error {
+	if policy.Mirror != nil {
+		err := m.SetMirrorSourceWithContext(ctx, &SetMirrorSourceInput{Bucket: bucket, MirrorSource: *policy.Mirror}, opts...)
+		if err != nil {
+			return err
+		}
+	}
+	if policy.Referer != nil {
+		err := m.SetRefererAntiLeechWithContext(ctx, &SetRefererAntiLeechInput{Bucket: bucket, RefererAntiLeech: *policy.Referer}, opts...)
+		if err != nil {
+			return err
+		}
+	}
+	if policy.Website != nil {
+		err := m.SetStaticWebsiteWithContext(ctx, &SetStaticWebsiteInput{Bucket: bucket, StaticWebsite: *policy.Website}, opts...)
+		if err != nil {
+			return err
+		}
+	}
+	if policy.MaxAge != nil {
+		if err := m.SetMaxAgeWithContext(ctx, &SetMaxAgeInput{Bucket: bucket, MaxAge: *policy.MaxAge}, opts...); err != nil {
+			return err
+		}
+	}
+	if policy.CORS != nil {
+		if err := m.SetCORSRulesWithContext(ctx, &SetCORSRulesInput{Bucket: bucket, Rules: policy.CORS}, opts...); err != nil {
+			return err
+		}
+	}
+	if policy.Events != nil {
+		return m.applyEventRules(ctx, bucket, policy.Events, opts...)
+	}
+	return nil
+}
+
+// applyEventRules 把存储空间的事件通知规则修改为rules
+func (m *BucketManager) applyEventRules(ctx context.Context, bucket string, rules []EventRule, opts ...request.Option) error {
+	current, err := m.ListEventRulesWithContext(ctx, &ListEventRulesInput{Bucket: bucket}, opts...)
+	if err != nil {
+		return err
+	}
+	exists := make(map[string]bool, len(current.Rules))
+	for _, r := range current.Rules {
+		exists[r.Name] = true
+	}
+	wanted := make(map[string]bool, len(rules))
+	for _, r := range rules {
+		wanted[r.Name] = true
+	}
+
+	for _, r := range current.Rules {
+		if wanted[r.Name] {
+			continue
+		}
+		if err := m.DeleteEventRuleWithContext(ctx, &DeleteEventRuleInput{Bucket: bucket, Name: r.Name}, opts...); err != nil {
+			return err
+		}
+	}
+	for _, r := range rules {
+		err := m.PutEventRuleWithContext(ctx, &PutEventRuleInput{Bucket: bucket, EventRule: r, Update: exists[r.Name]}, opts...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func boolString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,227 @@
+package kodo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QN-zhangzhuo/go-sdk/internal/encoding"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/credentials"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+)
+
+// DefaultCallbackReplayWindow 同一个回调请求在这个时间内重复出现会被当作重放拒绝
+const DefaultCallbackReplayWindow = 15 * time.Minute
+
+// ErrInvalidCallbackBody 回调请求的内容不能解码
+const ErrInvalidCallbackBody = "InvalidCallbackBodyError"
+
+// qiniuDateFormat X-Qiniu-Date头部的时间格式
+const qiniuDateFormat = "20060102T150405Z"
+
+type callbackBodyKey struct{}
+
+// CallbackHandler 是接收上传回调的http.Handler中间件
+//
+// 验证请求的QBox或者Qiniu(V2)签名， 验证通过之后调用Next, Next中依然可以读取请求的Body
+//   - 没有签名的请求返回401
+//   - 签名错误的请求返回403
+//   - 在ReplayWindow内重复的请求返回403
+//   - 设置了RequireSignedDate的时候， 不是Qiniu(V2)签名， 没有X-Qiniu-Date头部，
+//     或者X-Qiniu-Date和本地时间相差超过ReplayWindow的请求返回403
+//   - 获取密钥失败返回500
+//
+// 设置了NewBody的时候， 回调的内容会被解码到NewBody返回的值中， 在Next中用CallbackBody获取，
+// 解码失败返回400
+type CallbackHandler struct {
+	// 验证签名使用的密钥
+	Credentials *credentials.Credentials
+
+	// 防重放的时间窗口， 为0的时候使用DefaultCallbackReplayWindow, 小于0的时候不检查重放
+	// 签名和内容都相同的请求在收到之后的ReplayWindow内再次出现会被拒绝
+	ReplayWindow time.Duration
+
+	// 为true的时候要求请求带有签名覆盖的X-Qiniu-Date头部， 并且和本地时间相差不超过ReplayWindow,
+	// 这样超过时间窗口的重放也会被拒绝
+	// 只有Qiniu(V2)签名覆盖X-Qiniu-Date头部， 开启之后QBox签名的回调会被拒绝， 存储的上传回调使用QBox签名
+	RequireSignedDate bool
+
+	// 返回一个用于解码回调内容的指针， 比如func() interface{} { return &MyCallback{} }
+	// 为nil的时候不解码
+	NewBody func() interface{}
+
+	// 验证通过之后处理回调请求的Handler
+	Next http.Handler
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// NewCallbackHandler 返回一个CallbackHandler指针， 验证通过的回调请求交给next处理
+func NewCallbackHandler(creds *credentials.Credentials, next http.Handler) *CallbackHandler {
+	return &CallbackHandler{
+		Credentials: creds,
+		Next:        next,
+	}
+}
+
+// ServeHTTP 实现了http.Handler接口
+func (h *CallbackHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") == "" {
+		http.Error(w, "missing callback signature", http.StatusUnauthorized)
+		return
+	}
+	if h.Credentials == nil {
+		http.Error(w, "no credentials configured", http.StatusInternalServerError)
+		return
+	}
+	v, err := h.Credentials.Get()
+	if err != nil {
+		http.Error(w, "failed to retrieve credential value", http.StatusInternalServerError)
+		return
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		http.Error(w, "failed to read callback body", http.StatusBadRequest)
+		return
+	}
+	ok, err := v.VerifyCallback(r)
+	if err != nil {
+		http.Error(w, "failed to verify callback signature", http.StatusBadRequest)
+		return
+	}
+	if !ok {
+		http.Error(w, "invalid callback signature", http.StatusForbidden)
+		return
+	}
+	if !h.checkReplay(r, body) {
+		http.Error(w, "replayed callback request", http.StatusForbidden)
+		return
+	}
+
+	if h.NewBody != nil {
+		dst := h.NewBody()
+		if err := DecodeCallback(r, dst); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r = r.WithContext(context.WithValue(r.Context(), callbackBodyKey{}, dst))
+	}
+	if h.Next != nil {
+		h.Next.ServeHTTP(w, r)
+	}
+}
+
+// checkReplay 检查请求是否是重放的请求， 不是重放的时候返回true
+func (h *CallbackHandler) checkReplay(r *http.Request, body []byte) bool {
+	window := h.ReplayWindow
+	if window < 0 {
+		return true
+	}
+	if window == 0 {
+		window = DefaultCallbackReplayWindow
+	}
+	now := time.Now()
+	expire := now.Add(window)
+	if h.RequireSignedDate {
+		// 请求的时间必须被签名覆盖， 否则可以被篡改
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Qiniu ") {
+			return false
+		}
+		t, err := time.Parse(qiniuDateFormat, r.Header.Get("X-Qiniu-Date"))
+		if err != nil || t.Sub(now) > window || now.Sub(t) > window {
+			return false
+		}
+		// 超过t+window之后同样的请求会因为时间过期被拒绝， 不再需要记录
+		expire = t.Add(window)
+	}
+
+	sum := sha1.New()
+	sum.Write([]byte(r.Header.Get("Authorization")))
+	sum.Write([]byte{'\n'})
+	sum.Write(body)
+	key := hex.EncodeToString(sum.Sum(nil))
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.seen == nil {
+		h.seen = make(map[string]time.Time)
+	}
+	for k, expire := range h.seen {
+		if !now.Before(expire) {
+			delete(h.seen, k)
+		}
+	}
+	if _, ok := h.seen[key]; ok {
+		return false
+	}
+	h.seen[key] = expire
+	return true
+}
+
+// CallbackBody 返回CallbackHandler解码的回调内容， 没有设置NewBody的时候返回nil
+func CallbackBody(r *http.Request) interface{} {
+	return r.Context().Value(callbackBodyKey{})
+}
+
+// DecodeCallback 按照Content-Type把回调请求的内容解码到v中， 解码之后r.Body依然可以读取
+//
+// application/json使用encoding/json解码， application/x-www-form-urlencoded按照字段的json标签解码，
+// 所以同一个结构体可以同时用于两种callbackBodyType
+// v是*url.Values的时候直接保存表单的内容
+func DecodeCallback(r *http.Request, v interface{}) error {
+	body, err := readBody(r)
+	if err != nil {
+		return qerr.New(ErrInvalidCallbackBody, "failed to read callback body", err)
+	}
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch ct {
+	case "application/json":
+		if err := json.Unmarshal(body, v); err != nil {
+			return qerr.New(ErrInvalidCallbackBody, "failed to decode json callback body", err)
+		}
+	case "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return qerr.New(ErrInvalidCallbackBody, "failed to parse form callback body", err)
+		}
+		if values, ok := v.(*url.Values); ok {
+			*values = form
+			return nil
+		}
+		dec := encoding.NewDecoder()
+		dec.SetAliasTag("json")
+		if err := dec.Decode(v, form); err != nil {
+			return qerr.New(ErrInvalidCallbackBody, "failed to decode form callback body", err)
+		}
+	default:
+		return qerr.New(ErrInvalidCallbackBody, "unsupported callback content type: "+ct, nil)
+	}
+	return nil
+}
+
+// readBody 读取请求的Body, 然后恢复r.Body以便之后可以再次读取
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	r.ContentLength = int64(len(body))
+	return body, nil
+}
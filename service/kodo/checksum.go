@@ -0,0 +1,83 @@
+package kodo
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"hash"
+	"io"
+)
+
+// md5Reader 在发送请求体的同时计算数据的MD5, 不需要额外读取一遍数据
+// 请求重试的时候会Seek回数据的开头， 这时候重新开始计算
+type md5Reader struct {
+	r io.ReadSeeker
+
+	// 数据在r中的起始位置和长度
+	base int64
+	size int64
+
+	// 当前读取的位置和已经计算过的数据长度， 都是相对于base的
+	pos    int64
+	hashed int64
+
+	md5 hash.Hash
+}
+
+func newMD5Reader(r io.ReadSeeker) (*md5Reader, error) {
+	base, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(base, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return &md5Reader{
+		r:    r,
+		base: base,
+		size: end - base,
+		md5:  md5.New(),
+	}, nil
+}
+
+func (c *md5Reader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if c.pos == c.hashed {
+		c.md5.Write(p[:n])
+		c.hashed += int64(n)
+	}
+	c.pos += int64(n)
+	return n, err
+}
+
+func (c *md5Reader) Seek(offset int64, whence int) (int64, error) {
+	abs, err := c.r.Seek(offset, whence)
+	if err != nil {
+		return abs, err
+	}
+	c.pos = abs - c.base
+	if c.pos == 0 {
+		c.md5.Reset()
+		c.hashed = 0
+	}
+	return abs, nil
+}
+
+// complete 返回是否已经计算了所有数据的校验值
+func (c *md5Reader) complete() bool {
+	return c.hashed == c.size
+}
+
+// MD5 返回数据的MD5, 十六进制编码
+func (c *md5Reader) MD5() string {
+	return hex.EncodeToString(c.md5.Sum(nil))
+}
+
+// partMD5 返回分片数据的MD5, 十六进制编码， 作为Content-MD5发送给服务端校验
+func partMD5(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,438 @@
+package kodo
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/defs"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qetag"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+const (
+	// DefaultSyncConcurrency 同步目录的时候默认同时上传的文件数
+	DefaultSyncConcurrency = 4
+
+	// ErrSyncFailed 同步目录的时候部分文件上传或者删除失败
+	ErrSyncFailed = "SyncFailedError"
+)
+
+// 同步操作的类型
+const (
+	SyncOpUpload = "upload"
+	SyncOpDelete = "delete"
+)
+
+// 执行同步操作的原因
+const (
+	// SyncReasonNew 存储空间中没有对应的资源
+	SyncReasonNew = "new"
+
+	// SyncReasonSize 本地文件和资源的大小不一样
+	SyncReasonSize = "size"
+
+	// SyncReasonHash 本地文件和资源的qetag不一样
+	SyncReasonHash = "hash"
+
+	// SyncReasonExtra 资源在本地没有对应的文件， 只有设置了SyncInput.Delete的时候删除
+	SyncReasonExtra = "extra"
+)
+
+// Syncer 把本地目录同步到存储空间的前缀下
+//
+// 本地文件和资源按照相对路径对应， 比较的规则是:
+// 资源不存在的时候上传; 大小不一样的时候上传;
+// 大小一样， 本地文件的修改时间早于资源的上传时间的时候认为没有变化;
+// 否则计算本地文件的qetag和资源的hash比较， 不一样的时候上传
+//
+//	syncer := kodo.NewSyncer(svc)
+//	out, err := syncer.Sync(&kodo.SyncInput{LocalDir: "./public", Bucket: "bucket", Prefix: "www/", Delete: true})
+//	fmt.Println(out.Summary())
+type Syncer struct {
+	// 上传文件使用的Uploader
+	Uploader *Uploader
+
+	// 同时上传的文件数
+	Concurrency int
+
+	svc *Kodo
+}
+
+// NewSyncer 返回一个Syncer指针， options可以用来修改Syncer的默认配置
+func NewSyncer(svc *Kodo, options ...func(*Syncer)) *Syncer {
+	s := &Syncer{
+		Uploader:    NewUploader(svc),
+		Concurrency: DefaultSyncConcurrency,
+		svc:         svc,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// SyncInput 同步目录的输入参数
+type SyncInput struct {
+	// 本地目录
+	LocalDir string
+
+	// 存储空间的名字
+	Bucket string
+
+	// 资源名的前缀， 资源名是Prefix加上文件相对于LocalDir的路径， 不为空的时候一般以"/"结尾
+	Prefix string
+
+	// 只同步匹配的文件， 为空的时候同步所有文件
+	// 规则使用path.Match的语法， 包含"/"的规则匹配相对路径， 否则匹配文件名
+	Include []string
+
+	// 不同步匹配的文件， 规则和Include一样， 优先级高于Include
+	Exclude []string
+
+	// 为true的时候删除存储空间中本地没有对应文件的资源， 被Include和Exclude过滤掉的资源不会删除
+	Delete bool
+
+	// 为true的时候只比较， 不上传也不删除， SyncOutput.Actions是需要执行的操作
+	DryRun bool
+
+	// 为true的时候忽略修改时间， 大小一样的文件总是比较qetag
+	CheckHash bool
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+}
+
+// Validate 检查输入的参数
+func (i *SyncInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "SyncInput"}
+	if i.LocalDir == "" {
+		invalidParams.Add(request.NewErrParamRequired("LocalDir"))
+	}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	for n, p := range i.Include {
+		if _, err := path.Match(p, ""); err != nil {
+			invalidParams.Add(request.NewErrParamFormat(fmt.Sprintf("Include[%d]", n), "path.Match pattern", p))
+		}
+	}
+	for n, p := range i.Exclude {
+		if _, err := path.Match(p, ""); err != nil {
+			invalidParams.Add(request.NewErrParamFormat(fmt.Sprintf("Exclude[%d]", n), "path.Match pattern", p))
+		}
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// match 返回相对路径rel是否需要同步
+func (i *SyncInput) match(rel string) bool {
+	matchAny := func(patterns []string) bool {
+		for _, p := range patterns {
+			name := rel
+			if !strings.Contains(p, "/") {
+				name = path.Base(rel)
+			}
+			if ok, _ := path.Match(p, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+	if len(i.Include) > 0 && !matchAny(i.Include) {
+		return false
+	}
+	return !matchAny(i.Exclude)
+}
+
+// SyncAction 同步过程中的一个上传或者删除操作
+type SyncAction struct {
+	// 操作的类型， SyncOpUpload或者SyncOpDelete
+	Op string
+
+	// 执行操作的原因， SyncReasonXxx
+	Reason string
+
+	// 文件相对于LocalDir的路径， 使用"/"分隔
+	Path string
+
+	// 资源名
+	Key string
+
+	// 上传的时候是本地文件的大小， 删除的时候是资源的大小
+	Size int64
+
+	// 操作失败的时候的错误信息
+	// 同步被取消的时候， 还没有开始执行的操作的错误码是request.ErrCodeCanceled
+	Error error
+}
+
+// SyncOutput 同步目录的结果
+type SyncOutput struct {
+	// 执行了的操作， DryRun的时候是需要执行的操作
+	Actions []SyncAction
+
+	// 没有变化的文件数
+	Unchanged int
+
+	// 被Include和Exclude过滤掉的文件和资源数
+	Filtered int
+}
+
+// Failed 返回失败的操作
+func (o *SyncOutput) Failed() []SyncAction {
+	var failed []SyncAction
+	for _, a := range o.Actions {
+		if a.Error != nil {
+			failed = append(failed, a)
+		}
+	}
+	return failed
+}
+
+// Summary 返回同步结果的摘要， 比如"uploaded 3 (1.20MB), deleted 1, unchanged 10, filtered 2, failed 0"
+// 同步被取消之后没有执行的操作计入failed
+func (o *SyncOutput) Summary() string {
+	var uploaded, deleted, failed int
+	var bytes int64
+	for _, a := range o.Actions {
+		switch {
+		case a.Error != nil:
+			failed++
+		case a.Op == SyncOpUpload:
+			uploaded++
+			bytes += a.Size
+		case a.Op == SyncOpDelete:
+			deleted++
+		}
+	}
+	return fmt.Sprintf("uploaded %d (%s), deleted %d, unchanged %d, filtered %d, failed %d",
+		uploaded, defs.Size(bytes), deleted, o.Unchanged, o.Filtered, failed)
+}
+
+// localFile 本地目录中的一个文件
+type localFile struct {
+	path string
+	info fs.FileInfo
+}
+
+// Sync 同步本地目录到存储空间
+// 部分操作失败的时候同步不会中断， 返回错误码为ErrSyncFailed的错误， 失败的操作可以通过SyncOutput.Failed获取
+func (s *Syncer) Sync(input *SyncInput) (*SyncOutput, error) {
+	return s.SyncWithContext(context.Background(), input)
+}
+
+// SyncWithContext 和Sync一样， 可以使用ctx取消同步
+func (s *Syncer) SyncWithContext(ctx context.Context, input *SyncInput) (*SyncOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	out := &SyncOutput{}
+
+	local, err := s.walk(input, out)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := s.list(ctx, input, out)
+	if err != nil {
+		return nil, err
+	}
+
+	var rels []string
+	for rel := range local {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+	for _, rel := range rels {
+		f := local[rel]
+		action := SyncAction{Op: SyncOpUpload, Path: rel, Key: input.Prefix + rel, Size: f.info.Size()}
+		item, ok := remote[rel]
+		switch {
+		case !ok:
+			action.Reason = SyncReasonNew
+		case item.Fsize != f.info.Size():
+			action.Reason = SyncReasonSize
+		case !input.CheckHash && f.info.ModTime().UnixNano()/100 <= item.PutTime:
+		default:
+			etag, err := qetag.File(f.path)
+			if err != nil {
+				action.Error = err
+			} else if etag != item.Hash {
+				action.Reason = SyncReasonHash
+			}
+		}
+		if action.Reason == "" && action.Error == nil {
+			out.Unchanged++
+			continue
+		}
+		out.Actions = append(out.Actions, action)
+	}
+
+	var deletes []int
+	if input.Delete {
+		var keys []string
+		for rel := range remote {
+			if _, ok := local[rel]; !ok {
+				keys = append(keys, rel)
+			}
+		}
+		sort.Strings(keys)
+		for _, rel := range keys {
+			deletes = append(deletes, len(out.Actions))
+			out.Actions = append(out.Actions, SyncAction{
+				Op: SyncOpDelete, Reason: SyncReasonExtra, Path: rel, Key: input.Prefix + rel, Size: remote[rel].Fsize,
+			})
+		}
+	}
+
+	if !input.DryRun {
+		s.upload(ctx, input, local, out)
+		s.delete(ctx, input, deletes, out)
+	}
+	if err := ctx.Err(); err != nil {
+		return out, qerr.New(request.ErrCodeCanceled, "sync canceled", err)
+	}
+	if failed := out.Failed(); len(failed) > 0 {
+		return out, qerr.New(ErrSyncFailed, fmt.Sprintf("%d of %d sync actions failed, first: %s %s",
+			len(failed), len(out.Actions), failed[0].Op, failed[0].Path), failed[0].Error)
+	}
+	return out, nil
+}
+
+// walk 遍历本地目录， 返回相对路径到文件的映射， 只包含普通文件
+func (s *Syncer) walk(input *SyncInput, out *SyncOutput) (map[string]localFile, error) {
+	files := make(map[string]localFile)
+	err := filepath.WalkDir(input.LocalDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(input.LocalDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !input.match(rel) {
+			out.Filtered++
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files[rel] = localFile{path: p, info: info}
+		return nil
+	})
+	if err != nil {
+		return nil, qerr.New(qerr.ErrOpenFile, "failed to walk directory: "+input.LocalDir, err)
+	}
+	return files, nil
+}
+
+// list 列举前缀下的所有资源， 返回相对路径到资源信息的映射
+func (s *Syncer) list(ctx context.Context, input *SyncInput, out *SyncOutput) (map[string]ListItem, error) {
+	items := make(map[string]ListItem)
+	err := NewBucketManager(s.svc).ListObjectsPagesWithContext(ctx, &ListObjectsInput{
+		Bucket: input.Bucket,
+		Prefix: input.Prefix,
+		Region: input.Region,
+	}, func(page *ListObjectsOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			rel := strings.TrimPrefix(item.Key, input.Prefix)
+			// 以"/"结尾的资源是目录的占位资源， 在本地没有对应的文件
+			if rel == "" || strings.HasSuffix(rel, "/") {
+				continue
+			}
+			if !input.match(rel) {
+				out.Filtered++
+				continue
+			}
+			items[rel] = item
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// upload 并发上传需要上传的文件， 结果记录在对应的SyncAction中
+func (s *Syncer) upload(ctx context.Context, input *SyncInput, local map[string]localFile, out *SyncOutput) {
+	concurrency := s.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultSyncConcurrency
+	}
+	actions := make(chan *SyncAction)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for a := range actions {
+				_, a.Error = s.Uploader.UploadFileWithContext(ctx, &UploadFileInput{
+					PutPolicy: &PutPolicy{Scope: input.Bucket + ":" + a.Key},
+					Key:       qiniu.String(a.Key),
+					FilePath:  local[a.Path].path,
+					Region:    input.Region,
+				})
+			}
+		}()
+	}
+	for i := range out.Actions {
+		a := &out.Actions[i]
+		if a.Op != SyncOpUpload || a.Error != nil {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			a.Error = skippedActionError(err)
+			continue
+		}
+		actions <- a
+	}
+	close(actions)
+	wg.Wait()
+}
+
+// skippedActionError 返回同步被取消之后没有执行的操作的错误
+func skippedActionError(err error) error {
+	return qerr.New(request.ErrCodeCanceled, "sync canceled before the action started", err)
+}
+
+// delete 批量删除out.Actions中下标为indexes的资源
+func (s *Syncer) delete(ctx context.Context, input *SyncInput, indexes []int, out *SyncOutput) {
+	if len(indexes) == 0 {
+		return
+	}
+	if err := ctx.Err(); err != nil {
+		for _, i := range indexes {
+			out.Actions[i].Error = skippedActionError(err)
+		}
+		return
+	}
+	ops := make([]BatchOperation, len(indexes))
+	for n, i := range indexes {
+		ops[n] = &DeleteInput{Entry: Entry{Bucket: input.Bucket, Key: out.Actions[i].Key}}
+	}
+	ret, err := NewBucketManager(s.svc).BatchWithContext(ctx, &BatchInput{Operations: ops, Region: input.Region})
+	for n, i := range indexes {
+		switch {
+		case err != nil:
+			out.Actions[i].Error = err
+		case ret.Results[n].Error != nil && !isNotExistErr(ret.Results[n].Error):
+			out.Actions[i].Error = ret.Results[n].Error
+		}
+	}
+}
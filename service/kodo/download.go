@@ -0,0 +1,273 @@
+package kodo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/credentials"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qetag"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+// DefaultDownloadURLExpires 私有下载地址默认的有效期
+const DefaultDownloadURLExpires = time.Hour
+
+// ErrWriteResponse 下载的数据写入io.Writer失败
+const ErrWriteResponse = "WriteResponseError"
+
+// PublicURL 返回资源的公开下载地址
+// domain是存储空间绑定的域名， 可以带上scheme, 没有scheme的时候使用http
+// query是数据处理的参数， 比如"imageView2/2/w/100"， 为空的时候不加
+func PublicURL(domain, key, query string) string {
+	if !strings.HasPrefix(domain, "http://") && !strings.HasPrefix(domain, "https://") {
+		domain = "http://" + domain
+	}
+	u := strings.TrimRight(domain, "/") + "/" + escapeKey(key)
+	if query != "" {
+		u += "?" + query
+	}
+	return u
+}
+
+// SignURL 对下载地址rawURL签名， 返回在deadline之前有效的私有下载地址
+func SignURL(v *credentials.Value, rawURL string, deadline time.Time) string {
+	if strings.Contains(rawURL, "?") {
+		rawURL += "&e="
+	} else {
+		rawURL += "?e="
+	}
+	rawURL += strconv.FormatInt(deadline.Unix(), 10)
+	return rawURL + "&token=" + v.Sign([]byte(rawURL))
+}
+
+// PrivateURL 返回资源的私有下载地址， 有效期为expires, expires小于等于0的时候使用DefaultDownloadURLExpires
+func PrivateURL(creds *credentials.Credentials, domain, key, query string, expires time.Duration) (string, error) {
+	if creds == nil {
+		return "", qerr.New(credentials.ErrCredsRetrieve, "no credentials configured", nil)
+	}
+	v, err := creds.Get()
+	if err != nil {
+		return "", qerr.New(credentials.ErrCredsRetrieve, "failed to retrieve credential value", err)
+	}
+	if expires <= 0 {
+		expires = DefaultDownloadURLExpires
+	}
+	return SignURL(&v, PublicURL(domain, key, query), time.Now().Add(expires)), nil
+}
+
+// PrivateURL 使用Config.Credentials生成资源的私有下载地址
+func (c *Kodo) PrivateURL(domain, key, query string, expires time.Duration) (string, error) {
+	return PrivateURL(c.Config.Credentials, domain, key, query, expires)
+}
+
+// escapeKey 对资源名进行转义， 保留其中的"/"
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// GetObjectInput 下载资源的输入参数
+type GetObjectInput struct {
+	// 存储空间的名字， 没有设置Domain的时候必填
+	Bucket string
+
+	// 资源名
+	Key string
+
+	// 存储空间绑定的域名， 可以带上scheme
+	// 为空的时候通过存储区域的IoHost下载， 请求总是会被签名
+	Domain string
+
+	// 数据处理的参数， 比如"imageView2/2/w/100", 只有设置了Domain的时候有效
+	Query string
+
+	// 通过Domain下载的时候是否使用私有下载地址
+	Private bool
+
+	// 私有下载地址的有效期， 小于等于0的时候使用DefaultDownloadURLExpires
+	Expires time.Duration
+
+	// 下载的范围， 格式和http Range请求头一样， 比如"bytes=0-1023"
+	Range string
+
+	// 为true的时候， 下载的同时计算数据的qetag, 和响应的Etag不一致的时候返回qerr.ErrEtagMismatch
+	// 只对没有设置Range和Query的完整下载有效
+	VerifyEtag bool
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+}
+
+// Validate 检查输入的参数
+func (i *GetObjectInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "GetObjectInput"}
+	if i.Key == "" {
+		invalidParams.Add(request.NewErrParamRequired("Key"))
+	}
+	if i.Domain == "" && i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if i.Range != "" && !strings.HasPrefix(i.Range, "bytes=") {
+		invalidParams.Add(request.NewErrParamFormat("Range", "bytes=<start>-<end>", i.Range))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+func (i *GetObjectInput) signed() bool {
+	return i.Domain == "" || i.Private
+}
+
+// downloadURL 返回下载地址， ioHost用于没有设置Domain的时候
+func (i *GetObjectInput) downloadURL(v *credentials.Value, ioHost string) string {
+	if i.Domain != "" {
+		return PublicURL(i.Domain, i.Key, i.Query)
+	}
+	return PublicURL(ioHost, fmt.Sprintf("getfile/%s/%s/%s", v.AccessKey, i.Bucket, i.Key), "")
+}
+
+// GetObjectOutput 下载资源的结果
+type GetObjectOutput struct {
+	// 响应的状态码， 200或者206(Range下载)
+	StatusCode int
+
+	// 响应体的长度， 未知的时候为-1
+	ContentLength int64
+
+	// 资源的MimeType
+	ContentType string
+
+	// Range下载的时候， 返回的数据的范围
+	ContentRange string
+
+	// 资源的Etag, 已经去掉了两边的双引号
+	Etag string
+
+	// 资源的最后修改时间
+	LastModified string
+
+	// 写入io.Writer的字节数
+	Written int64
+}
+
+// GetObjectRequest 生成一个下载资源的请求， 响应体会直接写入w, 不会在内存中缓存
+// 数据已经开始写入w之后出错不会重试， 防止w中出现重复的数据
+func (c *Kodo) GetObjectRequest(input *GetObjectInput, w io.Writer) (req *request.Request, output *GetObjectOutput) {
+	if input == nil {
+		input = &GetObjectInput{}
+	}
+	output = &GetObjectOutput{}
+
+	op := &request.API{
+		Method:      "GET",
+		ServiceName: ServiceName,
+		APIName:     "GetObject",
+	}
+	if input.Domain != "" {
+		op.Host = input.Domain
+		op.Path = escapeKey(input.Key)
+		if input.Query != "" {
+			op.Path += "?" + input.Query
+		}
+	}
+	req = c.newRequest(op, nil, nil)
+	if input.Domain == "" {
+		c.setHosts(req, target{Region: input.Region, Bucket: input.Bucket}, oneHost(c.ioHost))
+	}
+	if input.Range != "" {
+		req.HTTPRequest.Header.Set("Range", input.Range)
+	}
+
+	if input.signed() {
+		req.Handlers.Sign.PushBack(signURLHandler(input.Expires, func(v *credentials.Value) string {
+			// 需要查询区域的时候IoHost在Build阶段才确定， 使用请求当前的域名
+			u := req.HTTPRequest.URL
+			return input.downloadURL(v, u.Scheme+"://"+u.Host)
+		}))
+	}
+
+	req.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+		resp := r.HTTPResponse
+		output.StatusCode = resp.StatusCode
+		output.ContentLength = resp.ContentLength
+		output.ContentType = resp.Header.Get("Content-Type")
+		output.ContentRange = resp.Header.Get("Content-Range")
+		output.Etag = strings.Trim(resp.Header.Get("Etag"), `"`)
+		output.LastModified = resp.Header.Get("Last-Modified")
+
+		var h *qetag.Hasher
+		dst := w
+		if input.VerifyEtag && input.Range == "" && input.Query == "" && resp.StatusCode == 200 {
+			h = qetag.New()
+			dst = io.MultiWriter(w, h)
+		}
+		n, err := io.Copy(dst, resp.Body)
+		output.Written = n
+		if err != nil {
+			r.Error = qerr.New(ErrWriteResponse, "failed to write response body", err)
+			r.Retryable = qiniu.Bool(n == 0)
+			return
+		}
+		if h != nil {
+			if err := qetag.Verify(output.Etag, h.Etag()); err != nil {
+				r.Error = err
+				r.Retryable = qiniu.Bool(false)
+			}
+		}
+	})
+
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return
+}
+
+// signURLHandler 返回一个对请求地址签名的Sign handler, 签名的地址由rawURL生成
+// 每次重试都重新生成地址， 防止签名参数重复
+func signURLHandler(expires time.Duration, rawURL func(v *credentials.Value) string) func(*request.Request) {
+	return func(r *request.Request) {
+		if r.Config.Credentials == nil {
+			r.Error = qerr.New(credentials.ErrCredsRetrieve, "no credentials configured", nil)
+			return
+		}
+		v, err := r.Config.Credentials.Get()
+		if err != nil {
+			r.Error = qerr.New(credentials.ErrCredsRetrieve, "failed to retrieve credential value", err)
+			return
+		}
+		if expires <= 0 {
+			expires = DefaultDownloadURLExpires
+		}
+		u, err := url.Parse(SignURL(&v, rawURL(&v), time.Now().Add(expires)))
+		if err != nil {
+			r.Error = qerr.New("InvalidEndpointURL", "invalid download url", err)
+			return
+		}
+		r.HTTPRequest.URL = u
+		request.SanitizeHostForHeader(r.HTTPRequest)
+	}
+}
+
+// GetObject 下载资源， 响应体写入w
+func (c *Kodo) GetObject(input *GetObjectInput, w io.Writer) (*GetObjectOutput, error) {
+	req, out := c.GetObjectRequest(input, w)
+	return out, req.Send()
+}
+
+// GetObjectWithContext 和GetObject一样， 可以使用ctx取消请求
+func (c *Kodo) GetObjectWithContext(ctx context.Context, input *GetObjectInput, w io.Writer, opts ...request.Option) (*GetObjectOutput, error) {
+	req, out := c.GetObjectRequest(input, w)
+	return out, sendWithContext(ctx, req, opts...)
+}
@@ -0,0 +1,487 @@
+package kodo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/defs"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qetag"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+const (
+	// DefaultDownloadConcurrency 分段下载默认的最大并发数
+	DefaultDownloadConcurrency = 4
+
+	// DefaultDownloadPartSize 分段下载默认的分段大小
+	DefaultDownloadPartSize = 8 * defs.MB
+
+	// DefaultDownloadPartRetries 分段下载的数据写了一部分之后失败， 默认重新下载这一段的次数
+	DefaultDownloadPartRetries = 3
+
+	// ErrRangeNotSupported 下载地址不支持Range请求， 无法分段下载
+	ErrRangeNotSupported = "RangeNotSupportedError"
+
+	// ErrObjectChanged 下载的过程中资源被修改了
+	ErrObjectChanged = "ObjectChangedError"
+)
+
+// Downloader 分段并发下载的管理器， 把资源按照Range分成多段并发下载， 支持断点续传
+//
+//	downloader := kodo.NewDownloader(svc, func(d *kodo.Downloader) {
+//		d.Concurrency = 8
+//	})
+//	out, err := downloader.DownloadFile(&kodo.DownloadInput{Bucket: "bucket", Key: "key"}, "/path/to/file")
+type Downloader struct {
+	// 分段的大小， 会向上取整为qetag.BlockSize的整数倍， 这样每一段可以独立计算qetag
+	PartSize int64
+
+	// 最大的并发下载数量
+	Concurrency int
+
+	// 一段数据写入了一部分之后请求失败， SDK不会重试这样的请求， 由Downloader重新下载这一段
+	// PartRetries是每一段最多重新下载的次数
+	PartRetries int
+
+	// 为true的时候， 下载完成之后比较资源的Etag和下载的数据的qetag, 不一致的时候返回qerr.ErrEtagMismatch
+	// 默认为true, 如果Domain返回的Etag不是qetag(比如经过了其他的CDN), 需要关闭
+	VerifyEtag bool
+
+	// 下载进度的实现， 默认为nil, 不输出下载进度
+	// Config.ProgressRecorder和Config.DisableRecorder只用于分片上传， 需要输出下载进度的时候可以使用NewDownloadLogRecorder
+	Recorder ProgressRecorder
+
+	svc *Kodo
+}
+
+// NewDownloader 返回一个Downloader指针， options可以用来修改Downloader的默认配置
+func NewDownloader(svc *Kodo, options ...func(*Downloader)) *Downloader {
+	d := &Downloader{
+		PartSize:    DefaultDownloadPartSize,
+		Concurrency: DefaultDownloadConcurrency,
+		PartRetries: DefaultDownloadPartRetries,
+		VerifyEtag:  true,
+		svc:         svc,
+	}
+	for _, option := range options {
+		option(d)
+	}
+	return d
+}
+
+// NewDownloadLogRecorder 返回一个使用logger输出下载进度的ProgressRecorder, 用于Downloader.Recorder
+//
+//	downloader := kodo.NewDownloader(svc, func(d *kodo.Downloader) {
+//		d.Recorder = kodo.NewDownloadLogRecorder(qiniu.NewDefaultLogger())
+//	})
+func NewDownloadLogRecorder(logger qiniu.Logger) ProgressRecorder {
+	return logProgressRecorder{logger: logger, op: "download"}
+}
+
+// DownloadInput 分段下载的输入参数
+type DownloadInput struct {
+	// 存储空间的名字， 没有设置Domain的时候必填
+	Bucket string
+
+	// 资源名
+	Key string
+
+	// 存储空间绑定的域名， 可以带上scheme
+	// 为空的时候通过存储区域的IoHost下载， 请求总是会被签名
+	Domain string
+
+	// 通过Domain下载的时候是否使用私有下载地址
+	Private bool
+
+	// 私有下载地址的有效期， 小于等于0的时候使用DefaultDownloadURLExpires
+	Expires time.Duration
+
+	// 保存下载进度的文件， 为空的时候不支持断点续传
+	// 下载中断后使用同一个进度文件和同一个io.WriterAt再次下载， 只下载还没有完成的部分
+	CheckpointFile string
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+}
+
+// Validate 检查输入的参数
+func (i *DownloadInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "DownloadInput"}
+	if i.Key == "" {
+		invalidParams.Add(request.NewErrParamRequired("Key"))
+	}
+	if i.Domain == "" && i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// object 返回下载Range范围内数据的输入参数
+func (i *DownloadInput) object(rng string) *GetObjectInput {
+	return &GetObjectInput{
+		Bucket:  i.Bucket,
+		Key:     i.Key,
+		Domain:  i.Domain,
+		Private: i.Private,
+		Expires: i.Expires,
+		Range:   rng,
+		Region:  i.Region,
+	}
+}
+
+// DownloadOutput 分段下载的结果
+type DownloadOutput struct {
+	// 资源的大小
+	Size int64
+
+	// 资源的Etag
+	Etag string
+
+	// 从进度文件中恢复的、不需要再次下载的数据大小
+	Resumed int64
+}
+
+// Download 分段并发下载资源， 数据写入w
+func (d *Downloader) Download(input *DownloadInput, w io.WriterAt) (*DownloadOutput, error) {
+	return d.DownloadWithContext(context.Background(), input, w)
+}
+
+// DownloadWithContext 和Download一样， 可以使用ctx取消下载
+func (d *Downloader) DownloadWithContext(ctx context.Context, input *DownloadInput, w io.WriterAt) (*DownloadOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	m := &multipartDownloader{d: d, input: input, w: w}
+	return m.run(ctx)
+}
+
+// DownloadFile 分段并发下载资源到本地文件
+// 没有设置CheckpointFile的时候使用path加上".checkpoint"后缀作为进度文件
+func (d *Downloader) DownloadFile(input *DownloadInput, path string) (*DownloadOutput, error) {
+	return d.DownloadFileWithContext(context.Background(), input, path)
+}
+
+// DownloadFileWithContext 和DownloadFile一样， 可以使用ctx取消下载
+func (d *Downloader) DownloadFileWithContext(ctx context.Context, input *DownloadInput, path string) (*DownloadOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	in := *input
+	if in.CheckpointFile == "" {
+		in.CheckpointFile = path + ".checkpoint"
+	}
+
+	// 续传的时候需要保留文件中已经下载的数据， 所以不能截断文件
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, qerr.New(qerr.ErrOpenFile, "failed to open file: "+path, err)
+	}
+	defer f.Close()
+
+	out, err := d.DownloadWithContext(ctx, &in, f)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Truncate(out.Size); err != nil {
+		return nil, qerr.New(ErrWriteResponse, "failed to truncate file: "+path, err)
+	}
+	return out, nil
+}
+
+// downloadCheckpoint 分段下载的进度， 保存在CheckpointFile中
+type downloadCheckpoint struct {
+	Etag     string                   `json:"etag"`
+	Size     int64                    `json:"size"`
+	PartSize int64                    `json:"partSize"`
+	Parts    []downloadCheckpointPart `json:"parts"`
+}
+
+// downloadCheckpointPart 已经下载完成的一段， Blocks是这一段数据的qetag分块SHA1
+type downloadCheckpointPart struct {
+	PartNumber int      `json:"partNumber"`
+	Blocks     [][]byte `json:"blocks"`
+}
+
+// loadCheckpoint 读取进度文件， 文件不存在或者已经损坏的时候返回nil
+func loadCheckpoint(path string) (*downloadCheckpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cp downloadCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, os.Remove(path)
+	}
+	return &cp, nil
+}
+
+// save 保存进度文件， 先写入临时文件然后重命名， 防止进程崩溃的时候进度文件只写了一半
+func (cp *downloadCheckpoint) save(path string) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// offsetWriter 从off开始把数据写入io.WriterAt
+type offsetWriter struct {
+	w   io.WriterAt
+	off int64
+}
+
+func (o *offsetWriter) Write(p []byte) (int, error) {
+	n, err := o.w.WriteAt(p, o.off)
+	o.off += int64(n)
+	return n, err
+}
+
+// multipartDownloader 保存一次分段下载的状态
+type multipartDownloader struct {
+	d     *Downloader
+	input *DownloadInput
+	w     io.WriterAt
+
+	partSize int64
+	cp       *downloadCheckpoint
+
+	mu         sync.Mutex
+	downloaded int64
+	err        error
+}
+
+func (m *multipartDownloader) logf(format string, args ...interface{}) {
+	cfg := m.d.svc.Config
+	if !cfg.LogLevel.Matches(qiniu.LogDebugMultipartDownload) || cfg.Logger == nil {
+		return
+	}
+	cfg.Logger.Log(fmt.Sprintf("DEBUG: multipart download %s: ", m.input.Key) + fmt.Sprintf(format, args...))
+}
+
+// stat 下载资源的第一个字节， 从响应中获取资源的大小和Etag
+// 通过Domain下载的时候不一定有存储空间的权限， 所以不使用Stat接口
+func (m *multipartDownloader) stat(ctx context.Context) (size int64, etag string, err error) {
+	out, err := m.d.svc.GetObjectWithContext(ctx, m.input.object("bytes=0-0"), ioutil.Discard)
+	if err != nil {
+		// 空资源不能满足任何Range
+		if qErr, ok := err.(qerr.Error); ok && qErr.Code() == qerr.ErrRangeNotSatisfiable {
+			return 0, out.Etag, nil
+		}
+		return 0, "", err
+	}
+	if out.StatusCode == 200 && out.ContentLength == 0 {
+		return 0, out.Etag, nil
+	}
+	if out.StatusCode != 206 {
+		return 0, "", qerr.New(ErrRangeNotSupported, fmt.Sprintf("range request responds with status %d", out.StatusCode), nil)
+	}
+	// Content-Range的格式为"bytes 0-0/<size>"
+	i := strings.LastIndex(out.ContentRange, "/")
+	if i < 0 {
+		return 0, "", qerr.New(ErrRangeNotSupported, "invalid Content-Range: "+out.ContentRange, nil)
+	}
+	size, err = strconv.ParseInt(out.ContentRange[i+1:], 10, 64)
+	if err != nil {
+		return 0, "", qerr.New(ErrRangeNotSupported, "invalid Content-Range: "+out.ContentRange, err)
+	}
+	return size, out.Etag, nil
+}
+
+// init 加载进度文件， 资源没有变化的时候从上次的进度继续下载
+func (m *multipartDownloader) init(size int64, etag string) {
+	if path := m.input.CheckpointFile; path != "" {
+		cp, err := loadCheckpoint(path)
+		if err != nil {
+			m.logf("load checkpoint failed: %v", err)
+		}
+		if cp != nil && cp.Etag == etag && cp.Size == size && cp.PartSize == m.partSize {
+			m.logf("resume download, %d parts downloaded", len(cp.Parts))
+			m.cp = cp
+			return
+		}
+		if cp != nil {
+			m.logf("discard stale checkpoint")
+		}
+	}
+	m.cp = &downloadCheckpoint{Etag: etag, Size: size, PartSize: m.partSize}
+}
+
+func (m *multipartDownloader) run(ctx context.Context) (*DownloadOutput, error) {
+	size, etag, err := m.stat(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m.partSize = (m.d.PartSize + qetag.BlockSize - 1) / qetag.BlockSize * qetag.BlockSize
+	if m.partSize <= 0 {
+		m.partSize = DefaultDownloadPartSize
+	}
+	m.init(size, etag)
+
+	done := make(map[int]bool, len(m.cp.Parts))
+	for _, p := range m.cp.Parts {
+		done[p.PartNumber] = true
+		m.downloaded += m.partLen(p.PartNumber)
+	}
+	out := &DownloadOutput{Size: size, Etag: etag, Resumed: m.downloaded}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	concurrency := m.d.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultDownloadConcurrency
+	}
+	partCount := int((size + m.partSize - 1) / m.partSize)
+	parts := make(chan int, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range parts {
+				if err := m.downloadPart(ctx, n); err != nil {
+					m.setErr(err)
+					cancel()
+				}
+			}
+		}()
+	}
+	for n := 1; n <= partCount; n++ {
+		if done[n] {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		parts <- n
+	}
+	close(parts)
+	wg.Wait()
+
+	if m.err == nil && ctx.Err() != nil {
+		m.err = qerr.New(request.ErrCodeCanceled, "download canceled", ctx.Err())
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	if m.d.VerifyEtag && etag != "" {
+		sort.Slice(m.cp.Parts, func(i, j int) bool {
+			return m.cp.Parts[i].PartNumber < m.cp.Parts[j].PartNumber
+		})
+		var blocks [][]byte
+		for _, p := range m.cp.Parts {
+			blocks = append(blocks, p.Blocks...)
+		}
+		if err := qetag.Verify(etag, qetag.Join(blocks)); err != nil {
+			// 下载的数据是错误的， 进度文件也没有保留的意义
+			m.removeCheckpoint()
+			return nil, err
+		}
+	}
+	m.removeCheckpoint()
+	m.logf("download done, %d parts, %s", partCount, defs.Size(size))
+	return out, nil
+}
+
+// partLen 返回第n段的数据大小
+func (m *multipartDownloader) partLen(n int) int64 {
+	start := int64(n-1) * m.partSize
+	if start+m.partSize > m.cp.Size {
+		return m.cp.Size - start
+	}
+	return m.partSize
+}
+
+// downloadPart 下载第n段数据， 写入了一部分之后失败的时候重新下载这一段
+func (m *multipartDownloader) downloadPart(ctx context.Context, n int) error {
+	start := int64(n-1) * m.partSize
+	size := m.partLen(n)
+	rng := fmt.Sprintf("bytes=%d-%d", start, start+size-1)
+
+	for attempt := 0; ; attempt++ {
+		h := qetag.New()
+		out, err := m.d.svc.GetObjectWithContext(ctx, m.input.object(rng), io.MultiWriter(&offsetWriter{w: m.w, off: start}, h))
+		if err == nil {
+			switch {
+			case out.StatusCode != 206:
+				return qerr.New(ErrRangeNotSupported, fmt.Sprintf("range request responds with status %d", out.StatusCode), nil)
+			case m.cp.Etag != "" && out.Etag != "" && out.Etag != m.cp.Etag:
+				return qerr.New(ErrObjectChanged, fmt.Sprintf("etag changed from %s to %s", m.cp.Etag, out.Etag), nil)
+			case out.Written != size:
+				err = qerr.New(ErrWriteResponse, fmt.Sprintf("part %d short write, expected %d bytes, got %d", n, size, out.Written), nil)
+			default:
+				m.partDone(n, size, h.Blocks())
+				return nil
+			}
+		}
+		if aerr, ok := err.(qerr.Error); !ok || aerr.Code() != ErrWriteResponse || attempt >= m.d.PartRetries || ctx.Err() != nil {
+			m.logf("download part %d failed: %v", n, err)
+			return err
+		}
+		m.logf("download part %d interrupted, retry %d: %v", n, attempt+1, err)
+	}
+}
+
+// partDone 记录下载完成的一段数据， 更新进度文件
+func (m *multipartDownloader) partDone(n int, size int64, blocks [][]byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cp.Parts = append(m.cp.Parts, downloadCheckpointPart{PartNumber: n, Blocks: blocks})
+	m.downloaded += size
+	if m.d.Recorder != nil {
+		m.d.Recorder.Progress(m.input.Bucket, m.input.Key, m.downloaded, m.cp.Size)
+	}
+	if path := m.input.CheckpointFile; path != "" {
+		if err := m.cp.save(path); err != nil {
+			m.logf("save checkpoint failed: %v", err)
+		}
+	}
+}
+
+func (m *multipartDownloader) removeCheckpoint() {
+	if path := m.input.CheckpointFile; path != "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			m.logf("remove checkpoint failed: %v", err)
+		}
+	}
+}
+
+func (m *multipartDownloader) setErr(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err == nil {
+		m.err = err
+	}
+}
@@ -0,0 +1,490 @@
+package kodo
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/credentials"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/defs"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+const (
+	// DefaultAsyncFetchConcurrency AsyncFetchAll默认同时提交和等待的任务数
+	DefaultAsyncFetchConcurrency = 16
+
+	// DefaultAsyncFetchMinDelay 等待异步抓取任务的时候， 第一次检查之后等待的时长
+	DefaultAsyncFetchMinDelay = time.Second
+
+	// DefaultAsyncFetchMaxDelay 等待异步抓取任务的时候， 两次检查之间最多等待的时长
+	DefaultAsyncFetchMaxDelay = 30 * time.Second
+)
+
+// ioRequest 生成一个在IoHost上的资源管理请求， 比如抓取和镜像预取
+func (m *BucketManager) ioRequest(method, apiName, region string, input rsOperation, data interface{}) *request.Request {
+	op := &request.API{
+		Method:      method,
+		Path:        input.opPath(),
+		ContentType: defs.CONTENT_TYPE_FORM,
+		TokenType:   credentials.TokenQBox,
+		ServiceName: ServiceName,
+		APIName:     apiName,
+	}
+	req := m.newRequest(op, nil, data)
+	m.setHosts(req, target{Region: region, Bucket: input.bucketName()}, oneHost(m.ioHost))
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return req
+}
+
+// FetchInput 同步抓取第三方资源的输入参数
+type FetchInput struct {
+	// 第三方资源的地址
+	URL string
+
+	// 保存到的存储空间
+	Bucket string
+
+	// 保存的文件名， 为空的时候使用资源内容的hash作为文件名
+	Key string
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+}
+
+// Validate 检查输入的参数
+func (i *FetchInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "FetchInput"}
+	if i.URL == "" {
+		invalidParams.Add(request.NewErrParamRequired("URL"))
+	}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+func (i *FetchInput) opPath() string {
+	to := base64.URLEncoding.EncodeToString([]byte(i.Bucket))
+	if i.Key != "" {
+		to = qiniu.EncodedEntry(i.Bucket, i.Key)
+	}
+	return "/fetch/" + base64.URLEncoding.EncodeToString([]byte(i.URL)) + "/to/" + to
+}
+
+func (i *FetchInput) bucketName() string {
+	return i.Bucket
+}
+
+// FetchOutput 抓取到的资源的信息
+type FetchOutput struct {
+	// 文件的Hash值
+	Hash string `json:"hash"`
+
+	// 保存的文件名
+	Key string `json:"key"`
+
+	// 文件大小， 单位Byte
+	Fsize int64 `json:"fsize"`
+
+	// 文件的MimeType
+	MimeType string `json:"mimeType"`
+}
+
+// FetchRequest 生成一个同步抓取第三方资源的请求， 资源抓取完成之后请求才返回， 适合小文件
+func (m *BucketManager) FetchRequest(input *FetchInput) (req *request.Request, output *FetchOutput) {
+	if input == nil {
+		input = &FetchInput{}
+	}
+	output = &FetchOutput{}
+	req = m.ioRequest("POST", "Fetch", input.Region, input, output)
+	return
+}
+
+// Fetch 抓取第三方资源保存到存储空间中
+func (m *BucketManager) Fetch(input *FetchInput) (*FetchOutput, error) {
+	req, out := m.FetchRequest(input)
+	return out, req.Send()
+}
+
+// FetchWithContext 和Fetch一样， 可以使用ctx取消请求
+func (m *BucketManager) FetchWithContext(ctx context.Context, input *FetchInput, opts ...request.Option) (*FetchOutput, error) {
+	req, out := m.FetchRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// PrefetchInput 镜像预取的输入参数
+type PrefetchInput struct {
+	Entry
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+}
+
+// Validate 检查输入的参数
+func (i *PrefetchInput) Validate() error {
+	return validateEntry("PrefetchInput", i.Entry)
+}
+
+func (i *PrefetchInput) opPath() string {
+	return "/prefetch/" + i.Encoded()
+}
+
+// PrefetchOutput 镜像预取的结果
+type PrefetchOutput struct{}
+
+// PrefetchRequest 生成一个镜像预取的请求
+// 从存储空间配置的镜像源拉取资源， 覆盖存储空间中已有的同名资源
+func (m *BucketManager) PrefetchRequest(input *PrefetchInput) (req *request.Request, output *PrefetchOutput) {
+	if input == nil {
+		input = &PrefetchInput{}
+	}
+	output = &PrefetchOutput{}
+	req = m.ioRequest("POST", "Prefetch", input.Region, input, nil)
+	return
+}
+
+// Prefetch 从镜像源更新存储空间中的资源
+func (m *BucketManager) Prefetch(input *PrefetchInput) (*PrefetchOutput, error) {
+	req, out := m.PrefetchRequest(input)
+	return out, req.Send()
+}
+
+// PrefetchWithContext 和Prefetch一样， 可以使用ctx取消请求
+func (m *BucketManager) PrefetchWithContext(ctx context.Context, input *PrefetchInput, opts ...request.Option) (*PrefetchOutput, error) {
+	req, out := m.PrefetchRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// AsyncFetchInput 异步抓取第三方资源的输入参数
+type AsyncFetchInput struct {
+	// 第三方资源的地址， 多个地址用;分隔， 抓取的时候依次尝试
+	URL string `json:"url"`
+
+	// 保存到的存储空间
+	Bucket string `json:"bucket"`
+
+	// 抓取的时候使用的Host头部
+	Host string `json:"host,omitempty"`
+
+	// 保存的文件名， 为空的时候使用资源内容的hash作为文件名
+	Key string `json:"key,omitempty"`
+
+	// 资源的MD5, 抓取之后校验
+	MD5 string `json:"md5,omitempty"`
+
+	// 资源的Etag, 抓取之后校验
+	Etag string `json:"etag,omitempty"`
+
+	// 抓取成功之后的回调
+	CallbackURL      string `json:"callbackurl,omitempty"`
+	CallbackBody     string `json:"callbackbody,omitempty"`
+	CallbackBodyType string `json:"callbackbodytype,omitempty"`
+	CallbackHost     string `json:"callbackhost,omitempty"`
+
+	// 文件的存储类型
+	FileType StorageClass `json:"file_type,omitempty"`
+
+	// 为true的时候， 存储空间中已经有同名的文件就不再抓取
+	IgnoreSameKey bool `json:"ignore_same_key,omitempty"`
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string `json:"-"`
+}
+
+// Validate 检查输入的参数
+func (i *AsyncFetchInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "AsyncFetchInput"}
+	if i.URL == "" {
+		invalidParams.Add(request.NewErrParamRequired("URL"))
+	}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// AsyncFetchOutput 提交异步抓取任务的结果
+type AsyncFetchOutput struct {
+	// 任务ID, 用于查询任务的状态
+	ID string `json:"id"`
+
+	// 排在该任务前面的任务数
+	Wait int `json:"wait"`
+}
+
+// AsyncFetchRequest 生成一个提交异步抓取任务的请求， 请求在APIHost上
+func (m *BucketManager) AsyncFetchRequest(input *AsyncFetchInput) (req *request.Request, output *AsyncFetchOutput) {
+	if input == nil {
+		input = &AsyncFetchInput{}
+	}
+	output = &AsyncFetchOutput{}
+
+	op := &request.API{
+		Method:      "POST",
+		Path:        "/sisyphus/fetch",
+		ContentType: defs.CONTENT_TYPE_JSON,
+		TokenType:   credentials.TokenQiniu,
+		ServiceName: ServiceName,
+		APIName:     "AsyncFetch",
+	}
+	req = m.newRequest(op, input, output)
+	m.setHosts(req, target{Region: input.Region, Bucket: input.Bucket}, oneHost(m.apiHost))
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return
+}
+
+// AsyncFetch 提交异步抓取任务， 可以使用WaitUntilAsyncFetchDone等待任务完成
+func (m *BucketManager) AsyncFetch(input *AsyncFetchInput) (*AsyncFetchOutput, error) {
+	req, out := m.AsyncFetchRequest(input)
+	return out, req.Send()
+}
+
+// AsyncFetchWithContext 和AsyncFetch一样， 可以使用ctx取消请求
+func (m *BucketManager) AsyncFetchWithContext(ctx context.Context, input *AsyncFetchInput, opts ...request.Option) (*AsyncFetchOutput, error) {
+	req, out := m.AsyncFetchRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// QueryAsyncFetchInput 查询异步抓取任务的输入参数
+type QueryAsyncFetchInput struct {
+	// 提交任务时返回的任务ID
+	ID string
+
+	// 任务保存到的存储空间， 用于选择请求的域名
+	Bucket string
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+}
+
+// Validate 检查输入的参数
+func (i *QueryAsyncFetchInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "QueryAsyncFetchInput"}
+	if i.ID == "" {
+		invalidParams.Add(request.NewErrParamRequired("ID"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// QueryAsyncFetchOutput 异步抓取任务的状态
+type QueryAsyncFetchOutput struct {
+	// 任务ID
+	ID string `json:"id"`
+
+	// 排在该任务前面的任务数， -1表示任务已经完成
+	Wait int `json:"wait"`
+}
+
+// Done 返回任务是否已经完成
+func (o *QueryAsyncFetchOutput) Done() bool {
+	return o.Wait == -1
+}
+
+// QueryAsyncFetchRequest 生成一个查询异步抓取任务状态的请求
+func (m *BucketManager) QueryAsyncFetchRequest(input *QueryAsyncFetchInput) (req *request.Request, output *QueryAsyncFetchOutput) {
+	if input == nil {
+		input = &QueryAsyncFetchInput{}
+	}
+	output = &QueryAsyncFetchOutput{}
+
+	op := &request.API{
+		Method:      "GET",
+		Path:        "/sisyphus/fetch?id=" + url.QueryEscape(input.ID),
+		TokenType:   credentials.TokenQiniu,
+		ServiceName: ServiceName,
+		APIName:     "QueryAsyncFetch",
+	}
+	req = m.newRequest(op, nil, output)
+	m.setHosts(req, target{Region: input.Region, Bucket: input.Bucket}, oneHost(m.apiHost))
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return
+}
+
+// QueryAsyncFetch 查询异步抓取任务的状态
+func (m *BucketManager) QueryAsyncFetch(input *QueryAsyncFetchInput) (*QueryAsyncFetchOutput, error) {
+	req, out := m.QueryAsyncFetchRequest(input)
+	return out, req.Send()
+}
+
+// QueryAsyncFetchWithContext 和QueryAsyncFetch一样， 可以使用ctx取消请求
+func (m *BucketManager) QueryAsyncFetchWithContext(ctx context.Context, input *QueryAsyncFetchInput, opts ...request.Option) (*QueryAsyncFetchOutput, error) {
+	req, out := m.QueryAsyncFetchRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// WaitUntilAsyncFetchDone 等待异步抓取任务完成
+func (m *BucketManager) WaitUntilAsyncFetchDone(input *QueryAsyncFetchInput) error {
+	return m.WaitUntilAsyncFetchDoneWithContext(context.Background(), input)
+}
+
+// WaitUntilAsyncFetchDoneWithContext 和WaitUntilAsyncFetchDone一样， 可以使用ctx取消等待
+//
+// 默认使用从DefaultAsyncFetchMinDelay到DefaultAsyncFetchMaxDelay的指数退避， 不限制检查次数，
+// 可以通过opts修改； 查询任务状态失败(比如任务不存在)的时候停止等待， 返回request.ErrCodeWaiterFailure
+func (m *BucketManager) WaitUntilAsyncFetchDoneWithContext(ctx context.Context, input *QueryAsyncFetchInput, opts ...request.WaiterOption) error {
+	w := request.Waiter{
+		Name:  "WaitUntilAsyncFetchDone",
+		Delay: request.BackoffWaiterDelay(DefaultAsyncFetchMinDelay, DefaultAsyncFetchMaxDelay),
+		NewRequest: func(opts []request.Option) (*request.Request, error) {
+			req, _ := m.QueryAsyncFetchRequest(input)
+			req.ApplyOptions(opts...)
+			return req, nil
+		},
+		Acceptor: func(req *request.Request, err error) request.WaiterState {
+			if err != nil {
+				return request.WaiterFailure
+			}
+			if req.Data.(*QueryAsyncFetchOutput).Done() {
+				return request.WaiterSuccess
+			}
+			return request.WaiterRetry
+		},
+	}
+	w.ApplyOptions(opts...)
+
+	return w.WaitWithContext(ctx)
+}
+
+// AsyncFetchAllInput 批量异步抓取的输入参数
+type AsyncFetchAllInput struct {
+	// 要提交的异步抓取任务
+	Fetches []*AsyncFetchInput
+
+	// 同时提交和等待的任务数， 小于等于0的时候使用DefaultAsyncFetchConcurrency
+	Concurrency int
+}
+
+// Validate 检查输入的参数
+func (i *AsyncFetchAllInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "AsyncFetchAllInput"}
+	if len(i.Fetches) == 0 {
+		invalidParams.Add(request.NewErrParamRequired("Fetches"))
+	}
+	for n, f := range i.Fetches {
+		if f == nil {
+			invalidParams.Add(request.NewErrParamRequired(fmt.Sprintf("Fetches[%d]", n)))
+			continue
+		}
+		if err := f.Validate(); err != nil {
+			if e, ok := err.(request.ErrInvalidParams); ok {
+				invalidParams.AddNested(fmt.Sprintf("Fetches[%d]", n), e)
+			}
+		}
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// AsyncFetchResult 一个异步抓取任务的结果
+type AsyncFetchResult struct {
+	// 任务ID, 提交失败的时候为空
+	ID string
+
+	// 提交或者等待任务失败的错误， 成功的时候为nil
+	Error error
+}
+
+// AsyncFetchAllOutput 批量异步抓取的结果， Results和输入的Fetches一一对应
+type AsyncFetchAllOutput struct {
+	Results []AsyncFetchResult
+}
+
+// Failed 返回失败的任务在Results中的下标
+func (o *AsyncFetchAllOutput) Failed() []int {
+	var failed []int
+	for i, r := range o.Results {
+		if r.Error != nil {
+			failed = append(failed, i)
+		}
+	}
+	return failed
+}
+
+// AsyncFetchAll 提交所有的异步抓取任务， 然后等待所有的任务完成
+func (m *BucketManager) AsyncFetchAll(input *AsyncFetchAllInput) (*AsyncFetchAllOutput, error) {
+	return m.AsyncFetchAllWithContext(context.Background(), input)
+}
+
+// AsyncFetchAllWithContext 和AsyncFetchAll一样， 可以使用ctx取消提交和等待
+//
+// 先提交所有的任务， 让任务尽早在服务端排队， 再等待提交成功的任务完成
+// 每个任务的结果保存在输出的Results中， 有任务失败的时候返回第一个失败的错误
+func (m *BucketManager) AsyncFetchAllWithContext(ctx context.Context, input *AsyncFetchAllInput, opts ...request.WaiterOption) (*AsyncFetchAllOutput, error) {
+	if input == nil {
+		input = &AsyncFetchAllInput{}
+	}
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	concurrency := input.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultAsyncFetchConcurrency
+	}
+
+	fetches := input.Fetches
+	output := &AsyncFetchAllOutput{Results: make([]AsyncFetchResult, len(fetches))}
+
+	run := func(fn func(i int)) {
+		var (
+			wg  sync.WaitGroup
+			sem = make(chan struct{}, concurrency)
+		)
+		for i := range fetches {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				fn(i)
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	run(func(i int) {
+		out, err := m.AsyncFetchWithContext(ctx, fetches[i])
+		output.Results[i] = AsyncFetchResult{ID: out.ID, Error: err}
+	})
+	run(func(i int) {
+		r := &output.Results[i]
+		if r.Error != nil {
+			return
+		}
+		r.Error = m.WaitUntilAsyncFetchDoneWithContext(ctx, &QueryAsyncFetchInput{
+			ID:     r.ID,
+			Bucket: fetches[i].Bucket,
+			Region: fetches[i].Region,
+		}, opts...)
+	})
+
+	for _, r := range output.Results {
+		if r.Error != nil {
+			return output, r.Error
+		}
+	}
+	return output, nil
+}
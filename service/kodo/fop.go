@@ -0,0 +1,246 @@
+package kodo
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu"
+)
+
+// PfopCommand 一个数据处理命令， 比如avthumb/mp4
+type PfopCommand interface {
+	// Command 返回命令的字符串形式
+	Command() string
+}
+
+// Fop 原始的数据处理命令字符串， 用于没有类型化的命令
+type Fop string
+
+// Command 实现了PfopCommand接口
+func (f Fop) Command() string {
+	return string(f)
+}
+
+// saveAs 把结果另存为指定文件的命令
+type saveAs struct {
+	cmd    PfopCommand
+	bucket string
+	key    string
+}
+
+func (s saveAs) Command() string {
+	return s.cmd.Command() + "|saveas/" + qiniu.EncodedEntry(s.bucket, s.key)
+}
+
+// SaveAs 返回一个把cmd的处理结果保存到存储空间bucket中， 文件名为key的命令
+func SaveAs(cmd PfopCommand, bucket, key string) PfopCommand {
+	return saveAs{cmd: cmd, bucket: bucket, key: key}
+}
+
+// JoinFops 用分号连接多个命令， 结果可以用于PutPolicy.PersistentOps
+func JoinFops(cmds ...PfopCommand) string {
+	s := make([]string, 0, len(cmds))
+	for _, c := range cmds {
+		if c != nil {
+			s = append(s, c.Command())
+		}
+	}
+	return strings.Join(s, ";")
+}
+
+// fopBuilder 按照/name/value的格式拼接命令的参数， 值为空的参数会被忽略
+type fopBuilder struct {
+	strings.Builder
+}
+
+func newFopBuilder(name string) *fopBuilder {
+	b := &fopBuilder{}
+	b.WriteString(name)
+	return b
+}
+
+func (b *fopBuilder) add(name, value string) *fopBuilder {
+	if value != "" {
+		b.WriteString("/" + name + "/" + value)
+	}
+	return b
+}
+
+func (b *fopBuilder) addInt(name string, value int) *fopBuilder {
+	if value > 0 {
+		b.add(name, strconv.Itoa(value))
+	}
+	return b
+}
+
+func (b *fopBuilder) addFloat(name string, value float64) *fopBuilder {
+	if value > 0 {
+		b.add(name, strconv.FormatFloat(value, 'f', -1, 64))
+	}
+	return b
+}
+
+func (b *fopBuilder) addBool(name string, value bool) *fopBuilder {
+	if value {
+		b.add(name, "1")
+	}
+	return b
+}
+
+// flag 添加一个没有值的参数， 比如imageMogr2的auto-orient
+func (b *fopBuilder) flag(name string, on bool) *fopBuilder {
+	if on {
+		b.WriteString("/" + name)
+	}
+	return b
+}
+
+func (b *fopBuilder) addEncoded(name, value string) *fopBuilder {
+	if value != "" {
+		b.add(name, base64.URLEncoding.EncodeToString([]byte(value)))
+	}
+	return b
+}
+
+// Avthumb 音视频转码命令
+type Avthumb struct {
+	// 目标格式， 比如mp4, m3u8, mp3
+	Format string
+
+	// 音频码率， 比如128k
+	AudioBitRate string
+
+	// 音频编码， 比如libfdk_aac
+	AudioCodec string
+
+	// 音频采样率， 单位Hz
+	SampleRate int
+
+	// 视频码率， 比如1.25m
+	VideoBitRate string
+
+	// 视频编码， 比如libx264
+	VideoCodec string
+
+	// 视频帧率
+	FrameRate int
+
+	// 分辨率， 比如1280x720
+	Resolution string
+
+	// 为true的时候按照原视频的宽高比缩放到Resolution之内
+	AutoScale bool
+
+	// 为true的时候去掉元数据
+	StripMeta bool
+
+	// 转码的开始时间， 单位秒
+	Start float64
+
+	// 转码的时长， 单位秒
+	Duration float64
+
+	// m3u8切片的时长， 单位秒
+	SegTime int
+
+	// 水印图片的地址
+	WatermarkImage string
+
+	// 水印的位置， 比如NorthWest, SouthEast
+	WatermarkGravity string
+}
+
+// Command 实现了PfopCommand接口
+func (a Avthumb) Command() string {
+	b := newFopBuilder("avthumb/" + a.Format)
+	b.add("ab", a.AudioBitRate).add("acodec", a.AudioCodec).addInt("ar", a.SampleRate)
+	b.add("vb", a.VideoBitRate).add("vcodec", a.VideoCodec).addInt("r", a.FrameRate)
+	b.add("s", a.Resolution).addBool("autoscale", a.AutoScale).addBool("stripmeta", a.StripMeta)
+	b.addFloat("ss", a.Start).addFloat("t", a.Duration).addInt("segtime", a.SegTime)
+	b.addEncoded("wmImage", a.WatermarkImage).add("wmGravity", a.WatermarkGravity)
+	return b.String()
+}
+
+// Vframe 视频截帧命令
+type Vframe struct {
+	// 图片格式， jpg或者png
+	Format string
+
+	// 截帧的时间点， 单位秒
+	Offset float64
+
+	// 图片的宽度和高度， 单位像素
+	Width  int
+	Height int
+
+	// 旋转的角度， 90, 180, 270或者auto
+	Rotate string
+}
+
+// Command 实现了PfopCommand接口
+func (v Vframe) Command() string {
+	b := newFopBuilder("vframe/" + v.Format)
+	b.add("offset", strconv.FormatFloat(v.Offset, 'f', -1, 64))
+	b.addInt("w", v.Width).addInt("h", v.Height).add("rotate", v.Rotate)
+	return b.String()
+}
+
+// Vsample 视频采样截图命令， 按照固定的间隔截取多张图片
+type Vsample struct {
+	// 图片格式， jpg或者png
+	Format string
+
+	// 采样的开始时间， 单位秒
+	Start float64
+
+	// 采样的时长， 单位秒
+	Duration float64
+
+	// 图片的宽度和高度， 单位像素
+	Width  int
+	Height int
+
+	// 采样的间隔， 单位秒
+	Interval float64
+
+	// 图片的文件名格式， 比如vframe-$(count)
+	Pattern string
+}
+
+// Command 实现了PfopCommand接口
+func (v Vsample) Command() string {
+	b := newFopBuilder("vsample/" + v.Format)
+	b.add("ss", strconv.FormatFloat(v.Start, 'f', -1, 64)).addFloat("t", v.Duration)
+	if v.Width > 0 || v.Height > 0 {
+		b.add("s", strconv.Itoa(v.Width)+"x"+strconv.Itoa(v.Height))
+	}
+	b.addFloat("interval", v.Interval).addEncoded("pattern", v.Pattern)
+	return b.String()
+}
+
+// Concat 音视频拼接命令， 把当前文件和URLs中的文件依次拼接起来
+type Concat struct {
+	// 拼接的模式， 默认为2
+	Mode int
+
+	// 目标格式， 比如mp4
+	Format string
+
+	// 拼接在当前文件之后的文件地址
+	URLs []string
+}
+
+// Command 实现了PfopCommand接口
+func (c Concat) Command() string {
+	mode := c.Mode
+	if mode <= 0 {
+		mode = 2
+	}
+	b := newFopBuilder("avconcat/" + strconv.Itoa(mode))
+	b.add("format", c.Format)
+	for _, u := range c.URLs {
+		b.WriteString("/" + base64.URLEncoding.EncodeToString([]byte(u)))
+	}
+	return b.String()
+}
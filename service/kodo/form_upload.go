@@ -0,0 +1,378 @@
+package kodo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/credentials"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+const (
+	// ErrBuildForm 构建表单上传请求体的时候发生错误
+	ErrBuildForm = "BuildFormError"
+)
+
+// FormUploadInput 表单上传的输入参数
+type FormUploadInput struct {
+	// 上传凭证， 如果为空， 会使用PutPolicy和Config.Credentials生成上传凭证
+	UpToken string
+
+	// 上传策略， 只有在UpToken为空的时候使用
+	PutPolicy *PutPolicy
+
+	// 资源名， 为nil的时候由服务端根据上传策略中的saveKey或者文件hash生成
+	Key *string
+
+	// 要上传的数据
+	Body io.Reader
+
+	// 原始的文件名， 用于魔法变量$(fname)
+	FileName string
+
+	// 文件的MimeType, 为空的时候由服务端自动判断
+	MimeType string
+
+	// 自定义变量， key必须以"x:"开头
+	CustomVars map[string]string
+
+	// 自定义的元数据， key必须以"x-qn-meta-"开头
+	Metadata map[string]string
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+
+	// 为true的时候不发送数据的CRC32
+	// 默认在发送数据的同时计算， 作为文件之后的crc32字段发送， 服务端校验失败返回406,
+	// 对应qerr.ErrCrc32Verification, Body可以Seek的时候请求会重试
+	DisableCrc32 bool
+}
+
+// Validate 检查输入的参数
+func (i *FormUploadInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "FormUploadInput"}
+	if i.UpToken == "" && i.PutPolicy == nil {
+		invalidParams.Add(request.NewErrParamRequired("UpToken"))
+	}
+	if i.PutPolicy != nil {
+		if err := i.PutPolicy.Validate(); err != nil {
+			invalidParams.AddNested("PutPolicy", err.(request.ErrInvalidParams))
+		}
+	}
+	if i.Body == nil {
+		invalidParams.Add(request.NewErrParamRequired("Body"))
+	}
+	for k := range i.CustomVars {
+		if !strings.HasPrefix(k, "x:") {
+			invalidParams.Add(request.NewErrParamFormat("CustomVars", "x:<name>", k))
+		}
+	}
+	for k := range i.Metadata {
+		if !strings.HasPrefix(k, "x-qn-meta-") {
+			invalidParams.Add(request.NewErrParamFormat("Metadata", "x-qn-meta-<name>", k))
+		}
+	}
+
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// target 返回上传的目标存储空间， 存储空间的名字来自上传策略或者上传凭证
+func (i *FormUploadInput) target() target {
+	t := target{Region: i.Region, UpToken: i.UpToken}
+	if i.UpToken == "" {
+		if i.PutPolicy != nil {
+			t.Bucket = i.PutPolicy.Bucket()
+		}
+	} else if p, err := DecodeUploadToken(i.UpToken); err == nil {
+		t.Bucket = p.Bucket()
+	}
+	return t
+}
+
+// PutRet 上传成功后服务端默认返回的数据
+// 如果上传策略中设置了ReturnBody, 需要自己定义相应的结构体
+type PutRet struct {
+	Key          string `json:"key"`
+	Hash         string `json:"hash"`
+	PersistentID string `json:"persistentId,omitempty"`
+}
+
+// FormUploadOutput 表单上传的返回结果
+type FormUploadOutput struct {
+	PutRet
+}
+
+// FormUploadRequest 生成一个表单上传的请求
+// 上传成功后， 返回的数据会被反序列化到ret中， ret为nil的时候使用FormUploadOutput
+func (c *Kodo) FormUploadRequest(input *FormUploadInput, ret interface{}) (req *request.Request, output *FormUploadOutput) {
+	if input == nil {
+		input = &FormUploadInput{}
+	}
+	output = &FormUploadOutput{}
+	if ret == nil {
+		ret = output
+	}
+
+	op := &request.API{
+		Method:      "POST",
+		Path:        "/",
+		ServiceName: ServiceName,
+		APIName:     "FormUpload",
+		TokenType:   credentials.TokenNone,
+	}
+
+	var body io.ReadSeeker
+	err := input.Validate()
+	if err == nil {
+		body, op.ContentType, err = c.buildForm(input)
+	}
+	if err != nil {
+		req = c.newUpRequest(op, input.target(), nil, ret)
+		req.Error = err
+		return
+	}
+
+	req = c.newUpRequest(op, input.target(), body, ret)
+	return
+}
+
+// FormUpload 使用表单的方式上传数据， 适合小文件的上传
+// 数据直接从Body发送， 不会被读取到内存中， Body没有实现io.ReadSeeker的时候请求失败不会重试
+// 大文件请使用分片上传
+func (c *Kodo) FormUpload(input *FormUploadInput) (*FormUploadOutput, error) {
+	req, out := c.FormUploadRequest(input, nil)
+	return out, req.Send()
+}
+
+// FormUploadWithContext 和FormUpload一样， 可以使用ctx取消请求
+func (c *Kodo) FormUploadWithContext(ctx context.Context, input *FormUploadInput, opts ...request.Option) (*FormUploadOutput, error) {
+	req, out := c.FormUploadRequest(input, nil)
+	req.SetContext(ctx)
+	req.ApplyOptions(opts...)
+	return out, req.Send()
+}
+
+// FormUploadWithRet 使用表单的方式上传数据， 服务端返回的数据会被反序列化到ret中
+// 当上传策略中设置了自定义的ReturnBody的时候使用该方法
+func (c *Kodo) FormUploadWithRet(ctx context.Context, input *FormUploadInput, ret interface{}, opts ...request.Option) error {
+	req, _ := c.FormUploadRequest(input, ret)
+	req.SetContext(ctx)
+	req.ApplyOptions(opts...)
+	return req.Send()
+}
+
+// uploadToken 返回上传凭证， 如果没有设置upToken, 使用上传策略生成上传凭证
+func (c *Kodo) uploadToken(upToken string, policy *PutPolicy) (string, error) {
+	if upToken != "" {
+		return upToken, nil
+	}
+	if c.Config.Credentials == nil {
+		return "", qerr.New(credentials.ErrCredsRetrieve, "credentials not configured", nil)
+	}
+	return policy.UploadToken(c.Config.Credentials)
+}
+
+// buildForm 把输入编码为multipart/form-data格式的请求体
+// 上传的数据直接从Body发送， 不会被读取到内存中， 数据的CRC32在发送的同时计算， 作为文件之后的crc32字段发送
+func (c *Kodo) buildForm(input *FormUploadInput) (io.ReadSeeker, string, error) {
+	token, err := c.uploadToken(input.UpToken, input.PutPolicy)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var head bytes.Buffer
+	w := multipart.NewWriter(&head)
+
+	fields := map[string]string{"token": token}
+	if input.Key != nil {
+		fields["key"] = *input.Key
+	}
+	for k, v := range input.CustomVars {
+		fields[k] = v
+	}
+	for k, v := range input.Metadata {
+		fields[k] = v
+	}
+	for k, v := range fields {
+		if err := w.WriteField(k, v); err != nil {
+			return nil, "", qerr.New(ErrBuildForm, "failed to write form field: "+k, err)
+		}
+	}
+
+	fileName := input.FileName
+	if fileName == "" && input.Key != nil {
+		fileName = filepath.Base(*input.Key)
+	}
+	if fileName == "" {
+		fileName = "filename"
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="file"; filename="`+escapeQuotes(fileName)+`"`)
+	if input.MimeType != "" {
+		h.Set("Content-Type", input.MimeType)
+	} else {
+		h.Set("Content-Type", "application/octet-stream")
+	}
+	if _, err := w.CreatePart(h); err != nil {
+		return nil, "", qerr.New(ErrBuildForm, "failed to create form file part", err)
+	}
+
+	form := &formBody{head: head.Bytes(), data: input.Body, size: -1, boundary: w.Boundary()}
+	if !input.DisableCrc32 {
+		form.crc = crc32.NewIEEE()
+	}
+	if data, ok := input.Body.(io.ReadSeeker); ok {
+		if form.base, err = data.Seek(0, io.SeekCurrent); err == nil {
+			if form.size, err = data.Seek(0, io.SeekEnd); err == nil {
+				form.size -= form.base
+				_, err = data.Seek(form.base, io.SeekStart)
+			}
+		}
+		if err != nil {
+			return nil, "", qerr.New(request.ErrCodeRead, "failed to seek upload data", err)
+		}
+		return form, w.FormDataContentType(), nil
+	}
+	// 不能Seek的数据使用chunked编码发送， 请求失败的时候不能重试
+	body := qiniu.ReadSeekCloser(struct{ io.Reader }{form})
+	return &body, w.FormDataContentType(), nil
+}
+
+// formBody 表单上传的请求体， 依次是表单字段和文件的头部， 上传的数据， crc32字段和结束边界
+// 上传的数据在发送的同时计算CRC32, 请求重试的时候Seek回数据的开头， 重新开始计算
+type formBody struct {
+	head     []byte
+	data     io.Reader
+	boundary string
+
+	// 数据在data中的起始位置和长度， data不能Seek的时候size在读取到结尾之前为-1
+	base int64
+	size int64
+
+	// 为nil的时候不发送crc32字段
+	crc hash.Hash32
+
+	// 当前读取的位置和已经计算过CRC32的数据长度， hashed是相对于数据的开头的
+	pos    int64
+	hashed int64
+
+	// 数据读取完之后生成的crc32字段和结束边界
+	tail []byte
+}
+
+// tailLen 返回crc32字段和结束边界的长度， crc32固定为10位十进制数， 这样发送之前就可以确定请求体的长度
+func (f *formBody) tailLen() int64 {
+	return int64(len(f.makeTail(0)))
+}
+
+func (f *formBody) makeTail(crc uint32) []byte {
+	var b bytes.Buffer
+	if f.crc != nil {
+		fmt.Fprintf(&b, "\r\n--%s\r\nContent-Disposition: form-data; name=\"crc32\"\r\n\r\n%010d", f.boundary, crc)
+	}
+	fmt.Fprintf(&b, "\r\n--%s--\r\n", f.boundary)
+	return b.Bytes()
+}
+
+func (f *formBody) Read(p []byte) (int, error) {
+	for {
+		headLen := int64(len(f.head))
+		if f.pos < headLen {
+			n := copy(p, f.head[f.pos:])
+			f.pos += int64(n)
+			return n, nil
+		}
+
+		off := f.pos - headLen
+		if f.size < 0 || off < f.size {
+			buf := p
+			if f.size >= 0 && int64(len(buf)) > f.size-off {
+				buf = buf[:f.size-off]
+			}
+			n, err := f.data.Read(buf)
+			if f.crc != nil && off == f.hashed {
+				f.crc.Write(buf[:n])
+				f.hashed += int64(n)
+			}
+			f.pos += int64(n)
+			if err == io.EOF {
+				if f.size >= 0 && off+int64(n) < f.size {
+					return n, io.ErrUnexpectedEOF
+				}
+				f.size, err = off+int64(n), nil
+			}
+			if n > 0 || err != nil {
+				return n, err
+			}
+			continue
+		}
+
+		if f.tail == nil {
+			if f.crc != nil && f.hashed != f.size {
+				return 0, qerr.New(request.ErrCodeRead, "upload data is not read sequentially, crc32 unavailable", nil)
+			}
+			var crc uint32
+			if f.crc != nil {
+				crc = f.crc.Sum32()
+			}
+			f.tail = f.makeTail(crc)
+		}
+		toff := off - f.size
+		if toff >= int64(len(f.tail)) {
+			return 0, io.EOF
+		}
+		n := copy(p, f.tail[toff:])
+		f.pos += int64(n)
+		return n, nil
+	}
+}
+
+// Seek 只有数据可以Seek的时候使用
+func (f *formBody) Seek(offset int64, whence int) (int64, error) {
+	headLen := int64(len(f.head))
+	total := headLen + f.size + f.tailLen()
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.pos
+	case io.SeekEnd:
+		offset += total
+	}
+	if offset < 0 || offset > total {
+		return 0, qerr.New(request.ErrCodeRead, "seek out of range", nil)
+	}
+	off := offset - headLen
+	if off < 0 {
+		off = 0
+	}
+	if off <= f.size {
+		if _, err := f.data.(io.Seeker).Seek(f.base+off, io.SeekStart); err != nil {
+			return 0, err
+		}
+	}
+	if off == 0 && f.crc != nil {
+		f.crc.Reset()
+		f.hashed = 0
+		f.tail = nil
+	}
+	f.pos = offset
+	return offset, nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
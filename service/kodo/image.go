@@ -0,0 +1,390 @@
+package kodo
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/credentials"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+// ImageView2 图片基本处理， 缩略图
+type ImageView2 struct {
+	// 缩放模式， 0到5
+	Mode int
+
+	// 目标图片的宽度和高度， 单位像素
+	Width  int
+	Height int
+
+	// 输出的格式， 比如jpg, png, webp
+	Format string
+
+	// 输出的质量， 1到100
+	Quality int
+
+	// 为true的时候输出渐进显示的jpg
+	Interlace bool
+
+	// 为true的时候处理失败返回原图
+	IgnoreError bool
+}
+
+// Command 实现了PfopCommand接口
+func (v ImageView2) Command() string {
+	b := newFopBuilder("imageView2/" + strconv.Itoa(v.Mode))
+	b.addInt("w", v.Width).addInt("h", v.Height).add("format", v.Format)
+	b.addInt("q", v.Quality).addBool("interlace", v.Interlace).addBool("ignore-error", v.IgnoreError)
+	return b.String()
+}
+
+// ImageMogr2 图片高级处理
+type ImageMogr2 struct {
+	// 为true的时候根据exif信息自动旋正
+	AutoOrient bool
+
+	// 缩放参数， 比如"!50p", "200x", "300x300>"
+	Thumbnail string
+
+	// 裁剪参数， 比如"!300x300a10a10"
+	Crop string
+
+	// 旋转的角度， 1到360
+	Rotate int
+
+	// 输出的格式， 比如jpg, png, webp
+	Format string
+
+	// 高斯模糊， 格式为"<radius>x<sigma>"
+	Blur string
+
+	// 输出的质量， 1到100
+	Quality int
+
+	// 为true的时候输出渐进显示的jpg
+	Interlace bool
+
+	// 为true的时候去掉图片的元信息
+	Strip bool
+}
+
+// Command 实现了PfopCommand接口
+func (m ImageMogr2) Command() string {
+	b := newFopBuilder("imageMogr2")
+	b.flag("auto-orient", m.AutoOrient).add("thumbnail", m.Thumbnail).add("crop", m.Crop)
+	b.addInt("rotate", m.Rotate).add("format", m.Format).add("blur", m.Blur)
+	b.addInt("quality", m.Quality).addBool("interlace", m.Interlace).flag("strip", m.Strip)
+	return b.String()
+}
+
+// Watermark 图片水印， 设置了Image的时候是图片水印， 否则是文字水印
+type Watermark struct {
+	// 水印图片的地址
+	Image string
+
+	// 水印文字
+	Text string
+
+	// 文字的字体和大小， 大小的单位是缇(1/20磅)
+	Font     string
+	FontSize int
+
+	// 文字的颜色， 比如#FFFFFF
+	Fill string
+
+	// 透明度， 1到100
+	Dissolve int
+
+	// 水印的位置， 比如NorthWest, SouthEast
+	Gravity string
+
+	// 水印的横向和纵向边距， 单位像素
+	Dx int
+	Dy int
+
+	// 图片水印相对于原图的短边的比例， 0到1
+	Scale float64
+}
+
+// Command 实现了PfopCommand接口
+func (w Watermark) Command() string {
+	var b *fopBuilder
+	if w.Image != "" {
+		b = newFopBuilder("watermark/1")
+		b.addEncoded("image", w.Image)
+	} else {
+		b = newFopBuilder("watermark/2")
+		b.addEncoded("text", w.Text).addEncoded("font", w.Font).addInt("fontsize", w.FontSize).addEncoded("fill", w.Fill)
+	}
+	b.addInt("dissolve", w.Dissolve).add("gravity", w.Gravity).addInt("dx", w.Dx).addInt("dy", w.Dy)
+	b.addFloat("ws", w.Scale)
+	return b.String()
+}
+
+// ImageProcess 图片处理的管道， 多个处理按照添加的顺序用"|"连接
+//
+//	query := kodo.NewImageProcess().
+//		Mogr2(kodo.ImageMogr2{AutoOrient: true, Thumbnail: "800x"}).
+//		Watermark(kodo.Watermark{Text: "qiniu", Gravity: "SouthEast"}).
+//		Slim().
+//		String()
+type ImageProcess struct {
+	cmds []string
+}
+
+// NewImageProcess 返回一个空的ImageProcess指针
+func NewImageProcess() *ImageProcess {
+	return &ImageProcess{}
+}
+
+// Then 添加一个处理命令
+func (p *ImageProcess) Then(cmd PfopCommand) *ImageProcess {
+	if cmd != nil {
+		if s := cmd.Command(); s != "" {
+			p.cmds = append(p.cmds, s)
+		}
+	}
+	return p
+}
+
+// View2 添加一个imageView2处理
+func (p *ImageProcess) View2(v ImageView2) *ImageProcess {
+	return p.Then(v)
+}
+
+// Mogr2 添加一个imageMogr2处理
+func (p *ImageProcess) Mogr2(m ImageMogr2) *ImageProcess {
+	return p.Then(m)
+}
+
+// Watermark 添加一个水印处理
+func (p *ImageProcess) Watermark(w Watermark) *ImageProcess {
+	return p.Then(w)
+}
+
+// Slim 添加图片瘦身处理
+func (p *ImageProcess) Slim() *ImageProcess {
+	return p.Then(Fop("imageslim"))
+}
+
+// Command 实现了PfopCommand接口， 可以和SaveAs一起用于持久化处理
+func (p *ImageProcess) Command() string {
+	return strings.Join(p.cmds, "|")
+}
+
+// String 返回下载地址中的处理参数
+func (p *ImageProcess) String() string {
+	return p.Command()
+}
+
+// URL 返回资源经过处理之后的公开下载地址
+func (p *ImageProcess) URL(domain, key string) string {
+	return PublicURL(domain, key, p.String())
+}
+
+// PrivateURL 返回资源经过处理之后的私有下载地址， 签名包含了处理参数
+func (p *ImageProcess) PrivateURL(creds *credentials.Credentials, domain, key string, expires time.Duration) (string, error) {
+	return PrivateURL(creds, domain, key, p.String(), expires)
+}
+
+// FopInfoInput 获取资源元信息(imageInfo, exif, avinfo)的输入参数
+type FopInfoInput struct {
+	// 存储空间绑定的域名， 可以带上scheme
+	Domain string
+
+	// 资源名
+	Key string
+
+	// 为true的时候使用私有下载地址
+	Private bool
+
+	// 私有下载地址的有效期， 小于等于0的时候使用DefaultDownloadURLExpires
+	Expires time.Duration
+}
+
+// Validate 检查输入的参数
+func (i *FopInfoInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "FopInfoInput"}
+	if i.Domain == "" {
+		invalidParams.Add(request.NewErrParamRequired("Domain"))
+	}
+	if i.Key == "" {
+		invalidParams.Add(request.NewErrParamRequired("Key"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// fopInfoRequest 生成一个获取资源元信息的请求， 响应由UnmarshalHandler解码到data中
+func (c *Kodo) fopInfoRequest(apiName, fop string, input *FopInfoInput, data interface{}) *request.Request {
+	if input == nil {
+		input = &FopInfoInput{}
+	}
+	op := &request.API{
+		Method:      "GET",
+		Host:        input.Domain,
+		Path:        escapeKey(input.Key) + "?" + fop,
+		ServiceName: ServiceName,
+		APIName:     apiName,
+	}
+	req := c.newRequest(op, nil, data)
+	if input.Private {
+		req.Handlers.Sign.PushBack(signURLHandler(input.Expires, func(*credentials.Value) string {
+			return PublicURL(input.Domain, input.Key, fop)
+		}))
+	}
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return req
+}
+
+// ImageInfoOutput 图片的基本信息
+type ImageInfoOutput struct {
+	// 图片的大小， 单位Byte
+	Size int64 `json:"size"`
+
+	// 图片的格式， 比如jpeg, png
+	Format string `json:"format"`
+
+	// 图片的宽度和高度， 单位像素
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// 颜色模型， 比如ycbcr
+	ColorModel string `json:"colorModel"`
+
+	// 图片的方向， 比如Top-left
+	Orientation string `json:"orientation,omitempty"`
+}
+
+// ImageInfoRequest 生成一个获取图片基本信息的请求
+func (c *Kodo) ImageInfoRequest(input *FopInfoInput) (req *request.Request, output *ImageInfoOutput) {
+	output = &ImageInfoOutput{}
+	req = c.fopInfoRequest("ImageInfo", "imageInfo", input, output)
+	return
+}
+
+// ImageInfo 获取图片的基本信息
+func (c *Kodo) ImageInfo(input *FopInfoInput) (*ImageInfoOutput, error) {
+	req, out := c.ImageInfoRequest(input)
+	return out, req.Send()
+}
+
+// ImageInfoWithContext 和ImageInfo一样， 可以使用ctx取消请求
+func (c *Kodo) ImageInfoWithContext(ctx context.Context, input *FopInfoInput, opts ...request.Option) (*ImageInfoOutput, error) {
+	req, out := c.ImageInfoRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// ExifValue 一个exif字段的值
+type ExifValue struct {
+	Val  string `json:"val"`
+	Type int    `json:"type"`
+}
+
+// ExifOutput 图片的exif信息
+type ExifOutput struct {
+	// exif字段， 比如Make, Model, DateTimeOriginal
+	Tags map[string]ExifValue
+}
+
+// UnmarshalJSON 实现了json.Unmarshaler接口， exif接口返回的是字段名到值的对象
+func (o *ExifOutput) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &o.Tags)
+}
+
+// ExifRequest 生成一个获取图片exif信息的请求
+func (c *Kodo) ExifRequest(input *FopInfoInput) (req *request.Request, output *ExifOutput) {
+	output = &ExifOutput{}
+	req = c.fopInfoRequest("Exif", "exif", input, output)
+	return
+}
+
+// Exif 获取图片的exif信息
+func (c *Kodo) Exif(input *FopInfoInput) (*ExifOutput, error) {
+	req, out := c.ExifRequest(input)
+	return out, req.Send()
+}
+
+// ExifWithContext 和Exif一样， 可以使用ctx取消请求
+func (c *Kodo) ExifWithContext(ctx context.Context, input *FopInfoInput, opts ...request.Option) (*ExifOutput, error) {
+	req, out := c.ExifRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// AvinfoStream 音视频的一个流
+type AvinfoStream struct {
+	Index         int    `json:"index"`
+	CodecName     string `json:"codec_name"`
+	CodecLongName string `json:"codec_long_name"`
+
+	// 流的类型， video或者audio
+	CodecType string `json:"codec_type"`
+
+	// 视频的宽度和高度， 单位像素
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+
+	// 视频的帧率， 比如25/1
+	FrameRate string `json:"r_frame_rate,omitempty"`
+
+	// 音频的采样率和声道数
+	SampleRate string `json:"sample_rate,omitempty"`
+	Channels   int    `json:"channels,omitempty"`
+
+	// 时长， 单位秒
+	Duration string `json:"duration"`
+
+	// 码率， 单位bit/s
+	BitRate string `json:"bit_rate"`
+}
+
+// AvinfoFormat 音视频的格式信息
+type AvinfoFormat struct {
+	NbStreams      int    `json:"nb_streams"`
+	FormatName     string `json:"format_name"`
+	FormatLongName string `json:"format_long_name"`
+
+	// 开始时间和时长， 单位秒
+	StartTime string `json:"start_time"`
+	Duration  string `json:"duration"`
+
+	// 文件大小， 单位Byte
+	Size string `json:"size"`
+
+	// 码率， 单位bit/s
+	BitRate string `json:"bit_rate"`
+
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// AvinfoOutput 音视频的元信息
+type AvinfoOutput struct {
+	Streams []AvinfoStream `json:"streams"`
+	Format  AvinfoFormat   `json:"format"`
+}
+
+// AvinfoRequest 生成一个获取音视频元信息的请求
+func (c *Kodo) AvinfoRequest(input *FopInfoInput) (req *request.Request, output *AvinfoOutput) {
+	output = &AvinfoOutput{}
+	req = c.fopInfoRequest("Avinfo", "avinfo", input, output)
+	return
+}
+
+// Avinfo 获取音视频的元信息
+func (c *Kodo) Avinfo(input *FopInfoInput) (*AvinfoOutput, error) {
+	req, out := c.AvinfoRequest(input)
+	return out, req.Send()
+}
+
+// AvinfoWithContext 和Avinfo一样， 可以使用ctx取消请求
+func (c *Kodo) AvinfoWithContext(ctx context.Context, input *FopInfoInput, opts ...request.Option) (*AvinfoOutput, error) {
+	req, out := c.AvinfoRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
@@ -0,0 +1,235 @@
+package kodo
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/credentials"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/defs"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+// MaxListLimit 一次列举最多返回的条目数
+const MaxListLimit = 1000
+
+// ListObjectsInput 列举资源的输入参数
+type ListObjectsInput struct {
+	// 存储空间的名字
+	Bucket string
+
+	// 只列举以Prefix开头的资源
+	Prefix string
+
+	// 目录分隔符， 比如"/"
+	// 设置之后， 资源名中Prefix之后第一个Delimiter之前的部分相同的资源会合并到CommonPrefixes中
+	Delimiter string
+
+	// 上一次列举返回的位置标记， 为空的时候从头开始列举
+	Marker string
+
+	// 本次列举最多返回的条目数， 范围1~1000, 为0的时候使用MaxListLimit
+	Limit int
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+}
+
+// Validate 检查输入的参数
+func (i *ListObjectsInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "ListObjectsInput"}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if i.Limit < 0 || i.Limit > MaxListLimit {
+		invalidParams.Add(request.NewErrParamFormat("Limit", "0~1000", strconv.Itoa(i.Limit)))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+func (i *ListObjectsInput) path() string {
+	v := make(url.Values)
+	v.Set("bucket", i.Bucket)
+	if i.Prefix != "" {
+		v.Set("prefix", i.Prefix)
+	}
+	if i.Delimiter != "" {
+		v.Set("delimiter", i.Delimiter)
+	}
+	if i.Marker != "" {
+		v.Set("marker", i.Marker)
+	}
+	limit := i.Limit
+	if limit == 0 {
+		limit = MaxListLimit
+	}
+	v.Set("limit", strconv.Itoa(limit))
+	return "/list?" + v.Encode()
+}
+
+// ListItem 列举返回的资源信息
+type ListItem struct {
+	// 资源名
+	Key string `json:"key"`
+
+	// 文件的Hash值
+	Hash string `json:"hash"`
+
+	// 文件大小， 单位Byte
+	Fsize int64 `json:"fsize"`
+
+	// 文件的MimeType
+	MimeType string `json:"mimeType"`
+
+	// 上传时间， 单位为100纳秒
+	PutTime int64 `json:"putTime"`
+
+	// 存储类型
+	Type StorageClass `json:"type"`
+
+	// 文件的状态， 0表示启用， 1表示禁用
+	Status int `json:"status"`
+
+	// 文件的MD5值， 只有部分文件有
+	MD5 string `json:"md5"`
+
+	// 上传时PutPolicy中指定的EndUser
+	EndUser string `json:"endUser"`
+}
+
+// ListObjectsOutput 列举资源的结果
+type ListObjectsOutput struct {
+	// 下一次列举的位置标记， 为空表示已经列举完毕
+	Marker string `json:"marker"`
+
+	// 设置了Delimiter的时候， 合并后的"目录"
+	CommonPrefixes []string `json:"commonPrefixes"`
+
+	// 资源列表
+	Items []ListItem `json:"items"`
+}
+
+// ListObjectsRequest 生成一个列举资源的请求
+// Marker无效的时候请求返回错误码为qerr.ErrInvalidMarker的错误， 可以清空Marker重新开始列举
+func (m *BucketManager) ListObjectsRequest(input *ListObjectsInput) (req *request.Request, output *ListObjectsOutput) {
+	if input == nil {
+		input = &ListObjectsInput{}
+	}
+	output = &ListObjectsOutput{}
+
+	op := &request.API{
+		Method:      "POST",
+		Path:        input.path(),
+		ContentType: defs.CONTENT_TYPE_FORM,
+		TokenType:   credentials.TokenQBox,
+		ServiceName: ServiceName,
+		APIName:     "ListObjects",
+	}
+	req = m.newRequest(op, nil, output)
+	m.setHosts(req, target{Region: input.Region, Bucket: input.Bucket}, oneHost(m.rsfHost))
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return
+}
+
+// ListObjects 列举存储空间中的资源， 一次最多返回MaxListLimit个条目
+// 需要列举所有资源的时候使用ListObjectsPages或者ListObjectsPaginator
+func (m *BucketManager) ListObjects(input *ListObjectsInput) (*ListObjectsOutput, error) {
+	req, out := m.ListObjectsRequest(input)
+	return out, req.Send()
+}
+
+// ListObjectsWithContext 和ListObjects一样， 可以使用ctx取消请求
+func (m *BucketManager) ListObjectsWithContext(ctx context.Context, input *ListObjectsInput, opts ...request.Option) (*ListObjectsOutput, error) {
+	req, out := m.ListObjectsRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// ListObjectsPages 从input.Marker开始依次列举每一页， 并调用fn
+// fn返回false或者已经列举完毕的时候停止， lastPage表示是否是最后一页
+func (m *BucketManager) ListObjectsPages(input *ListObjectsInput, fn func(page *ListObjectsOutput, lastPage bool) bool) error {
+	return m.ListObjectsPagesWithContext(context.Background(), input, fn)
+}
+
+// ListObjectsPagesWithContext 和ListObjectsPages一样， 可以使用ctx取消列举
+func (m *BucketManager) ListObjectsPagesWithContext(ctx context.Context, input *ListObjectsInput,
+	fn func(page *ListObjectsOutput, lastPage bool) bool, opts ...request.Option) error {
+
+	p := m.NewListObjectsPaginator(input, opts...)
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		if !fn(page, !p.HasMorePages()) {
+			break
+		}
+	}
+	return nil
+}
+
+// ListObjectsPaginator 按页列举存储空间中的资源， 自动使用上一页返回的Marker请求下一页
+//
+//	p := m.NewListObjectsPaginator(&kodo.ListObjectsInput{Bucket: "bucket"})
+//	for p.HasMorePages() {
+//	    page, err := p.NextPage(ctx)
+//	    if err != nil {
+//	        // 可以保存p.Marker()用于下次继续列举
+//	        return err
+//	    }
+//	    ...
+//	}
+//
+// ListObjectsPaginator不能在多个goroutine中并发使用
+type ListObjectsPaginator struct {
+	m     *BucketManager
+	input ListObjectsInput
+	opts  []request.Option
+
+	firstPage bool
+}
+
+// NewListObjectsPaginator 返回一个ListObjectsPaginator指针， input会被复制， 之后修改input不影响列举
+func (m *BucketManager) NewListObjectsPaginator(input *ListObjectsInput, opts ...request.Option) *ListObjectsPaginator {
+	p := &ListObjectsPaginator{m: m, opts: opts, firstPage: true}
+	if input != nil {
+		p.input = *input
+	}
+	return p
+}
+
+// HasMorePages 返回是否还有没有列举的页
+func (p *ListObjectsPaginator) HasMorePages() bool {
+	return p.firstPage || p.input.Marker != ""
+}
+
+// Marker 返回下一页的位置标记， 可以保存下来， 之后通过ListObjectsInput.Marker继续列举
+func (p *ListObjectsPaginator) Marker() string {
+	return p.input.Marker
+}
+
+// NextPage 列举下一页
+// 请求失败的时候不会前进， 再次调用NextPage会重试同一页
+// 如果返回的错误码为qerr.ErrInvalidMarker, 说明Marker已经失效， 需要使用新的Paginator从头列举
+func (p *ListObjectsPaginator) NextPage(ctx context.Context) (*ListObjectsOutput, error) {
+	if !p.HasMorePages() {
+		return nil, qerr.New(qerr.ErrParams, "no more pages", nil)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, qerr.New(request.ErrCodeCanceled, "request context canceled", err)
+	}
+
+	input := p.input
+	out, err := p.m.ListObjectsWithContext(ctx, &input, p.opts...)
+	if err != nil {
+		return nil, err
+	}
+	p.firstPage = false
+	p.input.Marker = out.Marker
+	return out, nil
+}
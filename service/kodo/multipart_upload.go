@@ -0,0 +1,359 @@
+package kodo
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/defs"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+const (
+	// MinPartSize 分片上传每个分片的最小值
+	MinPartSize = 1 * defs.MB
+
+	// MaxPartSize 分片上传每个分片的最大值
+	MaxPartSize = 1 * defs.GB
+
+	// MaxUploadParts 分片上传最多可以上传的分片数量
+	MaxUploadParts = 10000
+)
+
+// MultipartUpload 分片上传(v2)的目标信息， 分片上传的各个接口共用
+type MultipartUpload struct {
+	// 上传凭证
+	UpToken string
+
+	// 存储空间名字
+	Bucket string
+
+	// 资源名， 为nil的时候由服务端根据上传策略中的saveKey或者文件hash生成
+	Key *string
+
+	// 初始化分片上传的时候服务端返回的上传ID， 初始化接口不需要设置该值
+	UploadID string
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+}
+
+func (m *MultipartUpload) validate(invalidParams *request.ErrInvalidParams, withUploadID bool) {
+	if m.UpToken == "" {
+		invalidParams.Add(request.NewErrParamRequired("UpToken"))
+	}
+	if m.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if withUploadID && m.UploadID == "" {
+		invalidParams.Add(request.NewErrParamRequired("UploadID"))
+	}
+}
+
+// basePath 返回分片上传接口的路径前缀
+func (m *MultipartUpload) basePath() string {
+	encodedKey := "~"
+	if m.Key != nil {
+		encodedKey = base64.URLEncoding.EncodeToString([]byte(*m.Key))
+	}
+	return fmt.Sprintf("/buckets/%s/objects/%s/uploads", m.Bucket, encodedKey)
+}
+
+func (m *MultipartUpload) target() target {
+	return target{Region: m.Region, Bucket: m.Bucket, UpToken: m.UpToken}
+}
+
+func (m *MultipartUpload) authorization() string {
+	return "UpToken " + m.UpToken
+}
+
+// InitPartsInput 初始化分片上传的输入参数
+type InitPartsInput struct {
+	MultipartUpload
+}
+
+// Validate 检查输入的参数
+func (i *InitPartsInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "InitPartsInput"}
+	i.validate(&invalidParams, false)
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// InitPartsOutput 初始化分片上传的返回结果
+type InitPartsOutput struct {
+	// 上传ID， 后续的分片上传接口都要用到该值
+	UploadID string `json:"uploadId"`
+
+	// 上传ID的过期时间， Unix时间戳， 单位为秒
+	ExpireAt int64 `json:"expireAt"`
+}
+
+// InitPartsRequest 生成一个初始化分片上传的请求
+func (c *Kodo) InitPartsRequest(input *InitPartsInput) (req *request.Request, output *InitPartsOutput) {
+	if input == nil {
+		input = &InitPartsInput{}
+	}
+	output = &InitPartsOutput{}
+	op := &request.API{
+		Method:        "POST",
+		Path:          input.basePath(),
+		Authorization: input.authorization(),
+		ServiceName:   ServiceName,
+		APIName:       "InitParts",
+	}
+	req = c.newUpRequest(op, input.target(), nil, output)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return
+}
+
+// InitParts 初始化分片上传， 返回上传ID
+func (c *Kodo) InitParts(input *InitPartsInput) (*InitPartsOutput, error) {
+	req, out := c.InitPartsRequest(input)
+	return out, req.Send()
+}
+
+// InitPartsWithContext 和InitParts一样， 可以使用ctx取消请求
+func (c *Kodo) InitPartsWithContext(ctx context.Context, input *InitPartsInput, opts ...request.Option) (*InitPartsOutput, error) {
+	req, out := c.InitPartsRequest(input)
+	req.SetContext(ctx)
+	req.ApplyOptions(opts...)
+	return out, req.Send()
+}
+
+// UploadPartInput 上传一个分片的输入参数
+type UploadPartInput struct {
+	MultipartUpload
+
+	// 分片的编号， 从1开始， 最大为MaxUploadParts
+	PartNumber int
+
+	// 分片数据， 必须是可Seek的， 这样请求失败的时候可以重试
+	Body io.ReadSeeker
+
+	// 分片数据的MD5值， 十六进制编码， 可选， 设置后作为Content-MD5发送， 服务端会校验
+	// Uploader上传分片的时候会自动计算并设置这个值
+	ContentMD5 string
+}
+
+// Validate 检查输入的参数
+func (i *UploadPartInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "UploadPartInput"}
+	i.validate(&invalidParams, true)
+	if i.PartNumber < 1 {
+		invalidParams.Add(request.NewErrParamMinValue("PartNumber", 1))
+	}
+	if i.PartNumber > MaxUploadParts {
+		invalidParams.Add(request.NewErrParamFormat("PartNumber", fmt.Sprintf("<= %d", MaxUploadParts), fmt.Sprint(i.PartNumber)))
+	}
+	if i.Body == nil {
+		invalidParams.Add(request.NewErrParamRequired("Body"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// UploadPartOutput 上传一个分片的返回结果
+type UploadPartOutput struct {
+	// 分片的标识， 完成分片上传的时候需要用到
+	Etag string `json:"etag"`
+
+	// 服务端收到的分片数据的MD5值
+	// SDK同时在客户端比较发送的数据的MD5和这个值， 不一致的时候请求返回qerr.ErrCrc32Verification并重试
+	MD5 string `json:"md5"`
+}
+
+// UploadPartRequest 生成一个上传分片的请求
+func (c *Kodo) UploadPartRequest(input *UploadPartInput) (req *request.Request, output *UploadPartOutput) {
+	if input == nil {
+		input = &UploadPartInput{}
+	}
+	output = &UploadPartOutput{}
+	op := &request.API{
+		Method:        "PUT",
+		Path:          fmt.Sprintf("%s/%s/%d", input.basePath(), input.UploadID, input.PartNumber),
+		ContentType:   defs.CONTENT_TYPE_OCTET,
+		Authorization: input.authorization(),
+		ServiceName:   ServiceName,
+		APIName:       "UploadPart",
+	}
+	if err := input.Validate(); err != nil {
+		req = c.newUpRequest(op, input.target(), nil, output)
+		req.Error = err
+		return
+	}
+	body, err := newMD5Reader(input.Body)
+	if err != nil {
+		req = c.newUpRequest(op, input.target(), nil, output)
+		req.Error = qerr.New(request.ErrCodeRead, "failed to seek part body", err)
+		return
+	}
+	req = c.newUpRequest(op, input.target(), body, output)
+	req.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+		if r.Error != nil || !body.complete() {
+			return
+		}
+		if output.MD5 != "" && output.MD5 != body.MD5() {
+			// 406同样会重试， 只重新上传这一个分片
+			r.Error = qerr.New(qerr.ErrCrc32Verification,
+				fmt.Sprintf("part %d checksum mismatch, expected md5: %s, got: %s", input.PartNumber, body.MD5(), output.MD5), nil)
+		}
+	})
+	if input.ContentMD5 != "" {
+		req.HTTPRequest.Header.Set("Content-MD5", input.ContentMD5)
+	}
+	return
+}
+
+// UploadPart 上传一个分片
+func (c *Kodo) UploadPart(input *UploadPartInput) (*UploadPartOutput, error) {
+	req, out := c.UploadPartRequest(input)
+	return out, req.Send()
+}
+
+// UploadPartWithContext 和UploadPart一样， 可以使用ctx取消请求
+func (c *Kodo) UploadPartWithContext(ctx context.Context, input *UploadPartInput, opts ...request.Option) (*UploadPartOutput, error) {
+	req, out := c.UploadPartRequest(input)
+	req.SetContext(ctx)
+	req.ApplyOptions(opts...)
+	return out, req.Send()
+}
+
+// CompletedPart 已经上传成功的分片
+type CompletedPart struct {
+	PartNumber int    `json:"partNumber"`
+	Etag       string `json:"etag"`
+}
+
+// CompletePartsInput 完成分片上传的输入参数
+type CompletePartsInput struct {
+	MultipartUpload `json:"-"`
+
+	// 所有已经上传的分片， 必须按照PartNumber升序排列
+	Parts []CompletedPart `json:"parts"`
+
+	// 原始的文件名， 用于魔法变量$(fname)
+	FileName string `json:"fname,omitempty"`
+
+	// 文件的MimeType, 为空的时候由服务端自动判断
+	MimeType string `json:"mimeType,omitempty"`
+
+	// 自定义的元数据， key必须以"x-qn-meta-"开头
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// 自定义变量， key必须以"x:"开头
+	CustomVars map[string]string `json:"customVars,omitempty"`
+}
+
+// Validate 检查输入的参数
+func (i *CompletePartsInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "CompletePartsInput"}
+	i.validate(&invalidParams, true)
+	if len(i.Parts) == 0 {
+		invalidParams.Add(request.NewErrParamMinLen("Parts", 1))
+	}
+	for n := 1; n < len(i.Parts); n++ {
+		if i.Parts[n].PartNumber <= i.Parts[n-1].PartNumber {
+			invalidParams.Add(request.NewErrParamFormat("Parts", "ascending PartNumber", fmt.Sprint(i.Parts[n].PartNumber)))
+			break
+		}
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// CompletePartsRequest 生成一个完成分片上传的请求
+// 上传成功后， 返回的数据会被反序列化到ret中， ret为nil的时候使用PutRet
+func (c *Kodo) CompletePartsRequest(input *CompletePartsInput, ret interface{}) (req *request.Request, output *PutRet) {
+	if input == nil {
+		input = &CompletePartsInput{}
+	}
+	output = &PutRet{}
+	if ret == nil {
+		ret = output
+	}
+	op := &request.API{
+		Method:        "POST",
+		Path:          input.basePath() + "/" + input.UploadID,
+		ContentType:   defs.CONTENT_TYPE_JSON,
+		Authorization: input.authorization(),
+		ServiceName:   ServiceName,
+		APIName:       "CompleteParts",
+	}
+	req = c.newUpRequest(op, input.target(), input, ret)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return
+}
+
+// CompleteParts 完成分片上传， 服务端会把所有的分片合并成一个文件
+func (c *Kodo) CompleteParts(input *CompletePartsInput) (*PutRet, error) {
+	req, out := c.CompletePartsRequest(input, nil)
+	return out, req.Send()
+}
+
+// CompletePartsWithContext 和CompleteParts一样， 可以使用ctx取消请求
+func (c *Kodo) CompletePartsWithContext(ctx context.Context, input *CompletePartsInput, opts ...request.Option) (*PutRet, error) {
+	req, out := c.CompletePartsRequest(input, nil)
+	req.SetContext(ctx)
+	req.ApplyOptions(opts...)
+	return out, req.Send()
+}
+
+// AbortPartsInput 终止分片上传的输入参数
+type AbortPartsInput struct {
+	MultipartUpload
+}
+
+// Validate 检查输入的参数
+func (i *AbortPartsInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "AbortPartsInput"}
+	i.validate(&invalidParams, true)
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// AbortPartsRequest 生成一个终止分片上传的请求
+func (c *Kodo) AbortPartsRequest(input *AbortPartsInput) *request.Request {
+	if input == nil {
+		input = &AbortPartsInput{}
+	}
+	op := &request.API{
+		Method:        "DELETE",
+		Path:          input.basePath() + "/" + input.UploadID,
+		Authorization: input.authorization(),
+		ServiceName:   ServiceName,
+		APIName:       "AbortParts",
+	}
+	req := c.newUpRequest(op, input.target(), nil, nil)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return req
+}
+
+// AbortParts 终止分片上传， 服务端会删除已经上传的分片
+func (c *Kodo) AbortParts(input *AbortPartsInput) error {
+	return c.AbortPartsRequest(input).Send()
+}
+
+// AbortPartsWithContext 和AbortParts一样， 可以使用ctx取消请求
+func (c *Kodo) AbortPartsWithContext(ctx context.Context, input *AbortPartsInput, opts ...request.Option) error {
+	req := c.AbortPartsRequest(input)
+	req.SetContext(ctx)
+	req.ApplyOptions(opts...)
+	return req.Send()
+}
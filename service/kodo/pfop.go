@@ -0,0 +1,321 @@
+package kodo
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/credentials"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/defs"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+const (
+	// ErrPfopFailed 持久化处理失败
+	ErrPfopFailed = "PfopFailedError"
+
+	// ErrPfopCallbackFailed 持久化处理成功， 但是通知回调失败
+	ErrPfopCallbackFailed = "PfopCallbackFailedError"
+
+	// DefaultPfopMinDelay 等待持久化处理的时候， 第一次检查之后等待的时长
+	DefaultPfopMinDelay = 2 * time.Second
+
+	// DefaultPfopMaxDelay 等待持久化处理的时候， 两次检查之间最多等待的时长
+	DefaultPfopMaxDelay = time.Minute
+)
+
+// 持久化处理的状态码
+const (
+	// PfopSuccess 处理成功
+	PfopSuccess = 0
+
+	// PfopWaiting 等待处理
+	PfopWaiting = 1
+
+	// PfopProcessing 正在处理
+	PfopProcessing = 2
+
+	// PfopFailed 处理失败
+	PfopFailed = 3
+
+	// PfopCallbackFailed 处理成功， 但是通知回调失败
+	PfopCallbackFailed = 4
+)
+
+// OperationManager 持久化数据处理的客户端， 接口在APIHost上
+type OperationManager struct {
+	*Kodo
+}
+
+// NewOperationManager 返回一个OperationManager指针
+func NewOperationManager(svc *Kodo) *OperationManager {
+	return &OperationManager{Kodo: svc}
+}
+
+// PfopInput 提交持久化处理的输入参数
+type PfopInput struct {
+	// 要处理的文件所在的存储空间
+	Bucket string
+
+	// 要处理的文件名
+	Key string
+
+	// 处理命令， 依次执行， 可以用SaveAs指定结果保存的文件
+	Fops []PfopCommand
+
+	// 接收处理结果通知的URL
+	NotifyURL string
+
+	// 为true的时候， 即使结果文件已经存在也重新处理
+	Force bool
+
+	// 处理使用的队列名， 为空的时候使用公共队列
+	Pipeline string
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+}
+
+// Validate 检查输入的参数
+func (i *PfopInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "PfopInput"}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if i.Key == "" {
+		invalidParams.Add(request.NewErrParamRequired("Key"))
+	}
+	if JoinFops(i.Fops...) == "" {
+		invalidParams.Add(request.NewErrParamRequired("Fops"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+func (i *PfopInput) form() url.Values {
+	v := make(url.Values)
+	v.Set("bucket", i.Bucket)
+	v.Set("key", i.Key)
+	v.Set("fops", JoinFops(i.Fops...))
+	if i.NotifyURL != "" {
+		v.Set("notifyURL", i.NotifyURL)
+	}
+	if i.Force {
+		v.Set("force", "1")
+	}
+	if i.Pipeline != "" {
+		v.Set("pipeline", i.Pipeline)
+	}
+	return v
+}
+
+// PfopOutput 提交持久化处理的结果
+type PfopOutput struct {
+	// 持久化处理的ID, 用于查询处理的状态
+	PersistentID string `json:"persistentId"`
+}
+
+// PfopRequest 生成一个提交持久化处理的请求
+func (m *OperationManager) PfopRequest(input *PfopInput) (req *request.Request, output *PfopOutput) {
+	if input == nil {
+		input = &PfopInput{}
+	}
+	output = &PfopOutput{}
+
+	op := &request.API{
+		Method:      "POST",
+		Path:        "/pfop/",
+		ContentType: defs.CONTENT_TYPE_FORM,
+		TokenType:   credentials.TokenQBox,
+		ServiceName: ServiceName,
+		APIName:     "Pfop",
+	}
+	req = m.newRequest(op, strings.NewReader(input.form().Encode()), output)
+	m.setHosts(req, target{Region: input.Region, Bucket: input.Bucket}, oneHost(m.apiHost))
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return
+}
+
+// Pfop 对存储空间中的文件提交持久化处理
+func (m *OperationManager) Pfop(input *PfopInput) (*PfopOutput, error) {
+	req, out := m.PfopRequest(input)
+	return out, req.Send()
+}
+
+// PfopWithContext 和Pfop一样， 可以使用ctx取消请求
+func (m *OperationManager) PfopWithContext(ctx context.Context, input *PfopInput, opts ...request.Option) (*PfopOutput, error) {
+	req, out := m.PfopRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// PrefopInput 查询持久化处理状态的输入参数
+type PrefopInput struct {
+	// 持久化处理的ID
+	PersistentID string
+
+	// 处理的文件所在的存储空间， 用于选择请求的域名
+	Bucket string
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+}
+
+// Validate 检查输入的参数
+func (i *PrefopInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "PrefopInput"}
+	if i.PersistentID == "" {
+		invalidParams.Add(request.NewErrParamRequired("PersistentID"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// PrefopItem 一个处理命令的结果
+type PrefopItem struct {
+	// 处理命令
+	Cmd string `json:"cmd"`
+
+	// 命令的状态码， 取值为PfopSuccess等
+	Code int `json:"code"`
+
+	// 状态码的描述
+	Desc string `json:"desc"`
+
+	// 处理失败的原因
+	Error string `json:"error,omitempty"`
+
+	// 结果文件的Hash值
+	Hash string `json:"hash,omitempty"`
+
+	// 结果文件的文件名
+	Key string `json:"key,omitempty"`
+
+	// 为1的时候表示结果文件已经存在， 没有重新处理
+	ReturnOld int `json:"returnOld"`
+}
+
+// PrefopOutput 持久化处理的状态
+type PrefopOutput struct {
+	// 持久化处理的ID
+	ID string `json:"id"`
+
+	// 使用的队列名
+	Pipeline string `json:"pipeline"`
+
+	// 整个处理的状态码， 取值为PfopSuccess等
+	Code int `json:"code"`
+
+	// 状态码的描述
+	Desc string `json:"desc"`
+
+	// 处理的文件
+	InputBucket string `json:"inputBucket"`
+	InputKey    string `json:"inputKey"`
+
+	// 每个命令的结果， 和提交的命令一一对应
+	Items []PrefopItem `json:"items"`
+}
+
+// Done 返回处理是否已经结束
+func (o *PrefopOutput) Done() bool {
+	return o.Code != PfopWaiting && o.Code != PfopProcessing
+}
+
+// Failed 返回处理失败的命令在Items中的下标
+func (o *PrefopOutput) Failed() []int {
+	var failed []int
+	for i, item := range o.Items {
+		if item.Code == PfopFailed {
+			failed = append(failed, i)
+		}
+	}
+	return failed
+}
+
+// PrefopRequest 生成一个查询持久化处理状态的请求， 该请求不需要签名
+func (m *OperationManager) PrefopRequest(input *PrefopInput) (req *request.Request, output *PrefopOutput) {
+	if input == nil {
+		input = &PrefopInput{}
+	}
+	output = &PrefopOutput{}
+
+	op := &request.API{
+		Method:      "GET",
+		Path:        "/status/get/prefop?id=" + url.QueryEscape(input.PersistentID),
+		ServiceName: ServiceName,
+		APIName:     "Prefop",
+	}
+	req = m.newRequest(op, nil, output)
+	m.setHosts(req, target{Region: input.Region, Bucket: input.Bucket}, oneHost(m.apiHost))
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	}
+	return
+}
+
+// Prefop 查询持久化处理的状态
+func (m *OperationManager) Prefop(input *PrefopInput) (*PrefopOutput, error) {
+	req, out := m.PrefopRequest(input)
+	return out, req.Send()
+}
+
+// PrefopWithContext 和Prefop一样， 可以使用ctx取消请求
+func (m *OperationManager) PrefopWithContext(ctx context.Context, input *PrefopInput, opts ...request.Option) (*PrefopOutput, error) {
+	req, out := m.PrefopRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// WaitUntilPfopDone 等待持久化处理结束， 返回最后一次查询到的状态
+func (m *OperationManager) WaitUntilPfopDone(input *PrefopInput) (*PrefopOutput, error) {
+	return m.WaitUntilPfopDoneWithContext(context.Background(), input)
+}
+
+// WaitUntilPfopDoneWithContext 和WaitUntilPfopDone一样， 可以使用ctx取消等待
+//
+// 默认使用从DefaultPfopMinDelay到DefaultPfopMaxDelay的指数退避， 不限制检查次数， 可以通过opts修改
+// 处理失败(PfopFailed)的时候返回ErrPfopFailed, 处理成功但是通知回调失败(PfopCallbackFailed)的时候返回ErrPfopCallbackFailed,
+// 每个命令的结果在返回的PrefopOutput中
+func (m *OperationManager) WaitUntilPfopDoneWithContext(ctx context.Context, input *PrefopInput, opts ...request.WaiterOption) (*PrefopOutput, error) {
+	var last *PrefopOutput
+	w := request.Waiter{
+		Name:  "WaitUntilPfopDone",
+		Delay: request.BackoffWaiterDelay(DefaultPfopMinDelay, DefaultPfopMaxDelay),
+		NewRequest: func(opts []request.Option) (*request.Request, error) {
+			req, out := m.PrefopRequest(input)
+			req.ApplyOptions(opts...)
+			last = out
+			return req, nil
+		},
+		Acceptor: func(req *request.Request, err error) request.WaiterState {
+			if err != nil {
+				return request.WaiterFailure
+			}
+			if last.Done() {
+				return request.WaiterSuccess
+			}
+			return request.WaiterRetry
+		},
+	}
+	w.ApplyOptions(opts...)
+
+	if err := w.WaitWithContext(ctx); err != nil {
+		return last, err
+	}
+	switch last.Code {
+	case PfopFailed:
+		return last, qerr.New(ErrPfopFailed, fmt.Sprintf("pfop %s failed: %s", last.ID, last.Desc), nil)
+	case PfopCallbackFailed:
+		// 结果文件已经生成， 只是没有通知到NotifyURL
+		return last, qerr.New(ErrPfopCallbackFailed, fmt.Sprintf("pfop %s succeeded but callback failed: %s", last.ID, last.Desc), nil)
+	}
+	return last, nil
+}
@@ -0,0 +1,170 @@
+package kodo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/credentials"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+const (
+	// DefaultUploadTokenExpires 上传凭证默认的有效期
+	DefaultUploadTokenExpires = time.Hour
+)
+
+// PutPolicy 上传策略， 用来生成上传凭证
+// 各个字段的详细说明请参考:
+// https://developer.qiniu.com/kodo/manual/1206/put-policy
+type PutPolicy struct {
+	// 指定上传的目标资源空间和资源名， 格式为<bucket>或者<bucket>:<key>
+	Scope string `json:"scope"`
+
+	// 上传凭证的过期时间， Unix时间戳， 单位为秒
+	// 如果为0， 生成上传凭证的时候会使用Expires计算
+	Deadline uint32 `json:"deadline"`
+
+	// 上传凭证的有效期， 只有在Deadline为0的时候使用
+	// 如果为0， 使用DefaultUploadTokenExpires
+	Expires time.Duration `json:"-"`
+
+	// 为1的时候， Scope中的key作为前缀使用
+	IsPrefixalScope int `json:"isPrefixalScope,omitempty"`
+
+	// 为1的时候， 只允许新增文件， 不能覆盖已有的文件
+	InsertOnly int `json:"insertOnly,omitempty"`
+
+	// 唯一属主标识
+	EndUser string `json:"endUser,omitempty"`
+
+	// Web 端文件上传成功后，浏览器执行 303 跳转的 URL
+	ReturnURL string `json:"returnUrl,omitempty"`
+
+	// 上传成功后， 自定义七牛云最终返回給上传端的数据
+	ReturnBody string `json:"returnBody,omitempty"`
+
+	// 上传成功后， 七牛云向业务服务器发送 POST 请求的 URL
+	CallbackURL string `json:"callbackUrl,omitempty"`
+
+	// 上传成功后， 七牛云向业务服务器发送回调通知时的 Host 值
+	CallbackHost string `json:"callbackHost,omitempty"`
+
+	// 上传成功后， 七牛云向业务服务器发送 Content-Type: application/x-www-form-urlencoded 的 POST 请求
+	CallbackBody string `json:"callbackBody,omitempty"`
+
+	// 上传成功后， 七牛云向业务服务器发送回调通知 callbackBody 的 Content-Type
+	CallbackBodyType string `json:"callbackBodyType,omitempty"`
+
+	// 资源上传成功后触发执行的预转持久化处理指令列表， 多个指令之间以分号分隔， 可以用JoinFops生成
+	PersistentOps string `json:"persistentOps,omitempty"`
+
+	// 接收持久化处理结果通知的 URL
+	PersistentNotifyURL string `json:"persistentNotifyUrl,omitempty"`
+
+	// 转码队列名
+	PersistentPipeline string `json:"persistentPipeline,omitempty"`
+
+	// 为true的时候忽略客户端指定的key， 强制使用SaveKey进行文件命名
+	ForceSaveKey bool `json:"forceSaveKey,omitempty"`
+
+	// 自定义资源名
+	SaveKey string `json:"saveKey,omitempty"`
+
+	// 限定上传文件大小最小值， 单位Byte
+	FsizeMin int64 `json:"fsizeMin,omitempty"`
+
+	// 限定上传文件大小最大值， 单位Byte
+	FsizeLimit int64 `json:"fsizeLimit,omitempty"`
+
+	// 为1的时候， 服务端侦测文件的MimeType
+	DetectMime int `json:"detectMime,omitempty"`
+
+	// 限定用户上传的文件类型， 比如"image/*;video/*"
+	MimeLimit string `json:"mimeLimit,omitempty"`
+
+	// 文件存储类型。0 为普通存储， 1 为低频存储， 2 为归档存储
+	FileType int `json:"fileType,omitempty"`
+
+	// 文件在多少天后被删除， 0表示不删除
+	DeleteAfterDays int `json:"deleteAfterDays,omitempty"`
+}
+
+// Validate 检查上传策略的必填字段
+func (p *PutPolicy) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "PutPolicy"}
+	if p.Scope == "" {
+		invalidParams.Add(request.NewErrParamRequired("Scope"))
+	}
+	if p.FsizeMin < 0 {
+		invalidParams.Add(request.NewErrParamMinValue("FsizeMin", 0))
+	}
+	if p.FsizeLimit < 0 {
+		invalidParams.Add(request.NewErrParamMinValue("FsizeLimit", 0))
+	}
+	if p.DeleteAfterDays < 0 {
+		invalidParams.Add(request.NewErrParamMinValue("DeleteAfterDays", 0))
+	}
+
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// Bucket 返回上传策略中的存储空间名字
+func (p *PutPolicy) Bucket() string {
+	return strings.SplitN(p.Scope, ":", 2)[0]
+}
+
+// UploadToken 使用密钥cred生成上传凭证
+func (p *PutPolicy) UploadToken(cred *credentials.Credentials) (string, error) {
+	v, err := cred.Get()
+	if err != nil {
+		return "", qerr.New(credentials.ErrCredsRetrieve, "failed to retrieve credential value", err)
+	}
+	return p.UploadTokenWithValue(&v)
+}
+
+// UploadTokenWithValue 使用密钥v生成上传凭证
+func (p *PutPolicy) UploadTokenWithValue(v *credentials.Value) (string, error) {
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+
+	policy := *p
+	if policy.Deadline == 0 {
+		expires := policy.Expires
+		if expires <= 0 {
+			expires = DefaultUploadTokenExpires
+		}
+		policy.Deadline = uint32(time.Now().Add(expires).Unix())
+	}
+
+	data, err := json.Marshal(&policy)
+	if err != nil {
+		return "", qerr.New(request.ErrCodeSerialization, "failed to encode put policy", err)
+	}
+	return v.SignWithData(data), nil
+}
+
+// DecodeUploadToken 解析上传凭证， 返回凭证中的上传策略
+// 该函数不校验上传凭证的签名
+func DecodeUploadToken(token string) (*PutPolicy, error) {
+	splits := strings.Split(token, ":")
+	if len(splits) != 3 {
+		return nil, qerr.New(qiniu.ErrInvalidUptoken, "invalid upload token format", nil)
+	}
+	data, err := base64.URLEncoding.DecodeString(splits[2])
+	if err != nil {
+		return nil, qerr.New(qiniu.ErrInvalidUptoken, "invalid upload token format", err)
+	}
+	var p PutPolicy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, qerr.New(qiniu.ErrInvalidUptoken, "invalid put policy in upload token", err)
+	}
+	return &p, nil
+}
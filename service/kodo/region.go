@@ -0,0 +1,182 @@
+package kodo
+
+import (
+	"context"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/defs"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+const (
+	// ErrInvalidRegion 不支持的存储区域
+	ErrInvalidRegion = "InvalidRegionError"
+
+	// ErrNoAvailableHost 没有可用的域名
+	ErrNoAvailableHost = "NoAvailableHostError"
+)
+
+// target 请求的目标存储空间， 用于选择请求的域名
+type target struct {
+	// 接口输入中的区域
+	Region string
+
+	// 存储空间的名字， 为空的时候不能自动查询区域
+	Bucket string
+
+	// 上传凭证， 上传接口使用其中的AccessKey查询区域
+	UpToken string
+}
+
+// regionName 返回请求要使用的区域名字
+// 优先使用接口输入中的region, 其次是Config.Region, 都没有设置的时候使用defs.DefaultRegion
+func (c *Kodo) regionName(region string) string {
+	if region != "" {
+		return region
+	}
+	if r := qiniu.StringValue(c.Config.Region); r != "" {
+		return r
+	}
+	return defs.DefaultRegion
+}
+
+// queryable 返回是否需要通过UcHost查询存储空间所在的区域
+func (c *Kodo) queryable(t target) bool {
+	return t.Region == "" && qiniu.StringValue(c.Config.Region) == "" && t.Bucket != "" &&
+		c.Resolver != nil && !qiniu.BoolValue(c.Config.DisableRegionQuery)
+}
+
+// regionHost 返回存储区域的域名配置
+// 接口输入和Config都没有设置区域的时候， 通过Resolver查询存储空间所在的区域
+// Config.RegionHosts中的配置会覆盖该区域默认的域名配置
+func (c *Kodo) regionHost(ctx context.Context, t target) (*defs.Host, error) {
+	if c.queryable(t) {
+		ak, err := c.accessKey(t.UpToken)
+		if err != nil {
+			return nil, err
+		}
+		region, h, err := c.Resolver.Resolve(ctx, ak, t.Bucket)
+		if err != nil {
+			return nil, err
+		}
+		h.MergeIn(c.Config.RegionHosts[region])
+		return h, nil
+	}
+
+	region := c.regionName(t.Region)
+	h, ok := defs.DefaultRegionHost(region)
+	cfgHost := c.Config.RegionHosts[region]
+	if !ok {
+		if cfgHost == nil {
+			return nil, qerr.New(ErrInvalidRegion, "unsupported region: "+region, nil)
+		}
+		h = &defs.Host{}
+	}
+	h.MergeIn(cfgHost)
+	return h, nil
+}
+
+// upHosts 返回区域的上传域名列表， 配置了加速上传域名的时候， 加速上传域名排在前面
+func (c *Kodo) upHosts(ctx context.Context, t target) ([]string, error) {
+	h, err := c.regionHost(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	hosts := append(append([]string(nil), h.CdnUpHosts...), h.UpHosts...)
+	if len(hosts) == 0 {
+		return nil, qerr.New(ErrNoAvailableHost, "no up host configured for region: "+c.regionName(t.Region), nil)
+	}
+	return hosts, nil
+}
+
+// hostsFunc 返回请求可以使用的域名列表， 需要查询存储空间所在的区域的时候使用ctx发送查询请求
+type hostsFunc func(ctx context.Context, t target) ([]string, error)
+
+// oneHost 把只返回一个域名的函数转换为hostsFunc
+func oneHost(f func(ctx context.Context, t target) (string, error)) hostsFunc {
+	return func(ctx context.Context, t target) ([]string, error) {
+		host, err := f(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+		return []string{host}, nil
+	}
+}
+
+// newUpRequest 生成一个发送到t所在区域的上传域名的请求
+func (c *Kodo) newUpRequest(op *request.API, t target, params interface{}, data interface{}) *request.Request {
+	req := c.newRequest(op, params, data)
+	c.setHosts(req, t, c.upHosts)
+	return req
+}
+
+// setHosts 设置请求发送到的域名， 有多个域名的时候， 请求因为网络错误或者5xx失败的时候在域名之间切换
+// 需要查询存储空间所在的区域的时候， 在Build阶段使用请求的context查询， 查询可以和请求一起被取消
+func (c *Kodo) setHosts(req *request.Request, t target, hosts hostsFunc) {
+	set := func(r *request.Request) {
+		hs, err := hosts(r.Context(), t)
+		if err != nil {
+			r.Error = err
+			return
+		}
+		r.SetHost(hs[0])
+		if len(hs) > 1 {
+			sel := request.NewHostSelector(hs...)
+			sel.Freezer = c.HostFreezer
+			r.HostSelector = sel
+		}
+	}
+	if !c.queryable(t) {
+		set(req)
+		return
+	}
+	req.Handlers.Build.PushFront(func(r *request.Request) {
+		if r.Error == nil {
+			set(r)
+		}
+	})
+}
+
+// serviceHost 返回接口要使用的域名
+// 如果接口输入和Config都没有设置区域， 并且配置了全局的域名global, 使用全局的域名
+// global和SDK的默认值defaultGlobal一样的时候， 优先使用查询到的区域的域名
+// 其他情况使用区域的域名配置
+func (c *Kodo) serviceHost(ctx context.Context, t target, global *string, defaultGlobal string, pick func(*defs.Host) string) (string, error) {
+	g := qiniu.StringValue(global)
+	if t.Region == "" && qiniu.StringValue(c.Config.Region) == "" && g != "" &&
+		(g != defaultGlobal || !c.queryable(t)) {
+		return g, nil
+	}
+	h, err := c.regionHost(ctx, t)
+	if err != nil {
+		return "", err
+	}
+	if host := pick(h); host != "" {
+		return host, nil
+	}
+	if host := qiniu.StringValue(global); host != "" {
+		return host, nil
+	}
+	return "", qerr.New(ErrNoAvailableHost, "no host configured for region: "+c.regionName(t.Region), nil)
+}
+
+// rsHost 返回资源管理的域名
+func (c *Kodo) rsHost(ctx context.Context, t target) (string, error) {
+	return c.serviceHost(ctx, t, c.Config.RsHost, defs.DefaultRsHost, func(h *defs.Host) string { return h.RsHost })
+}
+
+// rsfHost 返回资源列举的域名
+func (c *Kodo) rsfHost(ctx context.Context, t target) (string, error) {
+	return c.serviceHost(ctx, t, c.Config.RsfHost, defs.DefaultRsfHost, func(h *defs.Host) string { return h.RsfHost })
+}
+
+// ioHost 返回存储下载入口的域名
+func (c *Kodo) ioHost(ctx context.Context, t target) (string, error) {
+	return c.serviceHost(ctx, t, nil, "", func(h *defs.Host) string { return h.IoHost })
+}
+
+// apiHost 返回存储API的域名， 比如异步抓取
+func (c *Kodo) apiHost(ctx context.Context, t target) (string, error) {
+	return c.serviceHost(ctx, t, c.Config.APIHost, defs.DefaultAPIHost, func(h *defs.Host) string { return h.APIHost })
+}
@@ -0,0 +1,301 @@
+package kodo
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/credentials"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/defs"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+// DefaultRegionTTL UC没有返回有效期的时候， 查询结果默认的缓存时间
+const DefaultRegionTTL = 24 * time.Hour
+
+// QueryRegionInput 查询存储空间所在区域的输入参数
+type QueryRegionInput struct {
+	// 存储空间所属账号的AccessKey, 为空的时候使用Config.Credentials
+	AccessKey string
+
+	// 存储空间的名字
+	Bucket string
+}
+
+// Validate 检查输入的参数
+func (i *QueryRegionInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "QueryRegionInput"}
+	if i.Bucket == "" {
+		invalidParams.Add(request.NewErrParamRequired("Bucket"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+type regionDomains struct {
+	Domains []string `json:"domains"`
+}
+
+// RegionInfo UC返回的一个区域的域名信息
+type RegionInfo struct {
+	// 区域的名字， 比如z0
+	Region string `json:"region"`
+
+	// 查询结果的有效期， 单位秒
+	TTL int64 `json:"ttl"`
+
+	Up  regionDomains `json:"up"`
+	Io  regionDomains `json:"io"`
+	Rs  regionDomains `json:"rs"`
+	Rsf regionDomains `json:"rsf"`
+	API regionDomains `json:"api"`
+}
+
+// Host 把查询结果转换为defs.Host
+// 如果是SDK已知的区域， 查询结果中没有的字段使用该区域的默认域名
+func (r *RegionInfo) Host() *defs.Host {
+	h, ok := defs.DefaultRegionHost(r.Region)
+	if !ok {
+		h = &defs.Host{}
+	}
+	first := func(d regionDomains) string {
+		if len(d.Domains) > 0 {
+			return d.Domains[0]
+		}
+		return ""
+	}
+	h.MergeIn(&defs.Host{
+		UpHosts: r.Up.Domains,
+		IoHost:  first(r.Io),
+		RsHost:  first(r.Rs),
+		RsfHost: first(r.Rsf),
+		APIHost: first(r.API),
+	})
+	if len(r.Up.Domains) > 0 {
+		// 查询结果中的上传域名已经按照优先级排好序， 包含了加速上传域名
+		h.CdnUpHosts = nil
+	}
+	return h
+}
+
+// QueryRegionOutput 查询存储空间所在区域的结果
+type QueryRegionOutput struct {
+	// 存储空间可用的区域， 第一个是存储空间所在的区域
+	Hosts []RegionInfo `json:"hosts"`
+}
+
+// QueryRegionRequest 生成一个向UcHost查询存储空间所在区域的请求， 该请求不需要签名
+func (c *Kodo) QueryRegionRequest(input *QueryRegionInput) (req *request.Request, output *QueryRegionOutput) {
+	if input == nil {
+		input = &QueryRegionInput{}
+	}
+	output = &QueryRegionOutput{}
+
+	ak := input.AccessKey
+	var akErr error
+	if ak == "" && input.Bucket != "" {
+		ak, akErr = c.accessKey("")
+	}
+	v := make(url.Values)
+	v.Set("ak", ak)
+	v.Set("bucket", input.Bucket)
+
+	op := &request.API{
+		Method:      "GET",
+		Path:        "/v4/query?" + v.Encode(),
+		Host:        qiniu.StringValue(c.Config.UcHost),
+		ServiceName: ServiceName,
+		APIName:     "QueryRegion",
+	}
+	req = c.newRequest(op, nil, output)
+	if err := input.Validate(); err != nil {
+		req.Error = err
+	} else if akErr != nil {
+		req.Error = akErr
+	} else if op.Host == "" {
+		req.Error = qerr.New(ErrNoAvailableHost, "no uc host configured", nil)
+	}
+	return
+}
+
+// QueryRegion 查询存储空间所在的区域和域名
+func (c *Kodo) QueryRegion(input *QueryRegionInput) (*QueryRegionOutput, error) {
+	req, out := c.QueryRegionRequest(input)
+	return out, req.Send()
+}
+
+// QueryRegionWithContext 和QueryRegion一样， 可以使用ctx取消请求
+func (c *Kodo) QueryRegionWithContext(ctx context.Context, input *QueryRegionInput, opts ...request.Option) (*QueryRegionOutput, error) {
+	req, out := c.QueryRegionRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// accessKey 返回查询区域使用的AccessKey, upToken不为空的时候从上传凭证中获取
+func (c *Kodo) accessKey(upToken string) (string, error) {
+	if upToken != "" {
+		if i := strings.IndexByte(upToken, ':'); i > 0 {
+			return upToken[:i], nil
+		}
+		return "", qerr.New(qiniu.ErrInvalidUptoken, "invalid upload token format", nil)
+	}
+	if c.Config.Credentials == nil {
+		return "", qerr.New(credentials.ErrCredsRetrieve, "no credentials configured", nil)
+	}
+	v, err := c.Config.Credentials.Get()
+	if err != nil {
+		return "", qerr.New(credentials.ErrCredsRetrieve, "failed to retrieve credential value", err)
+	}
+	return v.AccessKey, nil
+}
+
+// cachedRegion 缓存的区域查询结果
+type cachedRegion struct {
+	Region   string     `json:"region"`
+	Host     *defs.Host `json:"host"`
+	ExpireAt int64      `json:"expireAt"`
+}
+
+func (r *cachedRegion) expired() bool {
+	return time.Now().Unix() >= r.ExpireAt
+}
+
+// RegionResolver 通过UcHost查询存储空间所在的区域， 查询结果缓存在内存中，
+// 设置了CacheFile的时候同时缓存在磁盘上， 进程重启后依然有效
+//
+// 缓存过期后重新查询， 查询失败的时候继续使用过期的结果
+// RegionResolver可以在多个goroutine中并发使用
+type RegionResolver struct {
+	// 磁盘缓存文件的路径， 为空的时候只在内存中缓存
+	CacheFile string
+
+	svc *Kodo
+
+	mu      sync.Mutex
+	loaded  bool
+	entries map[string]*cachedRegion
+}
+
+// NewRegionResolver 返回一个RegionResolver指针， cacheFile为空的时候只在内存中缓存
+func NewRegionResolver(svc *Kodo, cacheFile string) *RegionResolver {
+	return &RegionResolver{
+		CacheFile: cacheFile,
+		svc:       svc,
+		entries:   make(map[string]*cachedRegion),
+	}
+}
+
+// Resolve 返回AccessKey为ak的账号下存储空间bucket所在的区域名字和域名配置
+func (r *RegionResolver) Resolve(ctx context.Context, ak, bucket string) (string, *defs.Host, error) {
+	key := ak + ":" + bucket
+
+	r.mu.Lock()
+	r.loadLocked()
+	entry := r.entries[key]
+	r.mu.Unlock()
+
+	if entry != nil && !entry.expired() {
+		return entry.Region, entry.Host.Copy(), nil
+	}
+
+	out, err := r.svc.QueryRegionWithContext(ctx, &QueryRegionInput{AccessKey: ak, Bucket: bucket})
+	if err == nil && len(out.Hosts) == 0 {
+		err = qerr.New(ErrInvalidRegion, "no region found for bucket: "+bucket, nil)
+	}
+	if err != nil {
+		if entry != nil {
+			return entry.Region, entry.Host.Copy(), nil
+		}
+		return "", nil, err
+	}
+
+	info := out.Hosts[0]
+	ttl := time.Duration(info.TTL) * time.Second
+	if ttl <= 0 {
+		ttl = DefaultRegionTTL
+	}
+	entry = &cachedRegion{Region: info.Region, Host: info.Host(), ExpireAt: time.Now().Add(ttl).Unix()}
+
+	r.mu.Lock()
+	r.entries[key] = entry
+	r.saveLocked()
+	r.mu.Unlock()
+
+	return entry.Region, entry.Host.Copy(), nil
+}
+
+// Invalidate 删除存储空间bucket的缓存， 下一次Resolve会重新查询
+func (r *RegionResolver) Invalidate(ak, bucket string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.loadLocked()
+	delete(r.entries, ak+":"+bucket)
+	r.saveLocked()
+}
+
+// loadLocked 第一次使用的时候从磁盘缓存中读取查询结果， 文件不存在或者损坏的时候忽略
+func (r *RegionResolver) loadLocked() {
+	if r.loaded {
+		return
+	}
+	r.loaded = true
+	if r.entries == nil {
+		r.entries = make(map[string]*cachedRegion)
+	}
+	if r.CacheFile == "" {
+		return
+	}
+	data, err := ioutil.ReadFile(r.CacheFile)
+	if err != nil {
+		return
+	}
+	var entries map[string]*cachedRegion
+	if json.Unmarshal(data, &entries) != nil {
+		return
+	}
+	for k, e := range entries {
+		if e != nil && e.Host != nil {
+			r.entries[k] = e
+		}
+	}
+}
+
+// saveLocked 把查询结果写入磁盘缓存， 磁盘缓存只是优化， 写入失败的时候忽略
+func (r *RegionResolver) saveLocked() {
+	if r.CacheFile == "" {
+		return
+	}
+	data, err := json.Marshal(r.entries)
+	if err != nil {
+		return
+	}
+	dir := filepath.Dir(r.CacheFile)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return
+	}
+	tmp, err := ioutil.TempFile(dir, filepath.Base(r.CacheFile)+".tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if cErr := tmp.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if os.Rename(tmp.Name(), r.CacheFile) != nil {
+		os.Remove(tmp.Name())
+	}
+}
@@ -0,0 +1,380 @@
+package kodo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/credentials"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/defs"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+// StatGranularity 统计数据的时间粒度
+type StatGranularity string
+
+// 统计数据的时间粒度
+const (
+	Granularity5Min  StatGranularity = "5min"
+	GranularityHour  StatGranularity = "hour"
+	GranularityDay   StatGranularity = "day"
+	GranularityMonth StatGranularity = "month"
+)
+
+// StatMetric 统计项
+type StatMetric string
+
+// 统计项
+const (
+	// MetricSpace 存储空间的使用量， 单位为字节
+	MetricSpace StatMetric = "space"
+
+	// MetricCount 文件数量
+	MetricCount StatMetric = "count"
+
+	// MetricPutRequests PUT请求次数
+	MetricPutRequests StatMetric = "put"
+
+	// MetricGetRequests GET请求次数
+	MetricGetRequests StatMetric = "get"
+
+	// MetricFlowOut 外网流出流量， 单位为字节
+	MetricFlowOut StatMetric = "flow_out"
+)
+
+// statTimeFormat 统计接口的时间格式
+const statTimeFormat = "20060102150405"
+
+// statLocation 统计接口的时间参数按北京时间解释
+var statLocation = time.FixedZone("CST", 8*3600)
+
+// StatisticsManager 存储统计数据的客户端， 接口在Config.APIHost上
+type StatisticsManager struct {
+	*Kodo
+}
+
+// NewStatisticsManager 返回一个StatisticsManager指针
+func NewStatisticsManager(svc *Kodo) *StatisticsManager {
+	return &StatisticsManager{Kodo: svc}
+}
+
+// StatPoint 统计数据的一个点
+type StatPoint struct {
+	// 统计时间段的开始时间
+	Time time.Time
+
+	// 统计值
+	Value int64
+}
+
+// GetStatisticsInput 获取统计数据的输入参数
+type GetStatisticsInput struct {
+	// 统计项
+	Metric StatMetric
+
+	// 存储空间的名字， 为空的时候统计所有的存储空间
+	Bucket string
+
+	// 存储区域， 为空的时候统计所有的区域
+	Region string
+
+	// 统计的时间范围， 包括Begin, 不包括End, 可以使用任意时区， 发送的时候转换为北京时间
+	Begin time.Time
+	End   time.Time
+
+	// 时间粒度， 为空的时候使用GranularityDay
+	Granularity StatGranularity
+}
+
+// Validate 检查输入的参数
+func (i *GetStatisticsInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "GetStatisticsInput"}
+	if _, _, ok := i.Metric.api(); !ok {
+		invalidParams.Add(request.NewErrParamFormat("Metric", "space, count, put, get or flow_out", string(i.Metric)))
+	}
+	switch i.Granularity {
+	case "", Granularity5Min, GranularityHour, GranularityDay, GranularityMonth:
+	default:
+		invalidParams.Add(request.NewErrParamFormat("Granularity", "5min, hour, day or month", string(i.Granularity)))
+	}
+	if i.Begin.IsZero() {
+		invalidParams.Add(request.NewErrParamRequired("Begin"))
+	}
+	if i.End.IsZero() {
+		invalidParams.Add(request.NewErrParamRequired("End"))
+	} else if !i.End.After(i.Begin) {
+		invalidParams.Add(request.NewErrParamFormat("End", "after Begin", i.End.Format(time.RFC3339)))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+func (i *GetStatisticsInput) granularity() StatGranularity {
+	if i.Granularity == "" {
+		return GranularityDay
+	}
+	return i.Granularity
+}
+
+// api 返回统计项对应的接口路径和select参数， select为空的接口返回times/datas格式的数据
+func (m StatMetric) api() (path, sel string, ok bool) {
+	switch m {
+	case MetricSpace:
+		return "/v6/space", "", true
+	case MetricCount:
+		return "/v6/count", "", true
+	case MetricPutRequests:
+		return "/v6/rs_put", "hits", true
+	case MetricGetRequests:
+		return "/v6/blob_io", "hits", true
+	case MetricFlowOut:
+		return "/v6/blob_io", "flow", true
+	}
+	return "", "", false
+}
+
+func (i *GetStatisticsInput) path() string {
+	path, sel, _ := i.Metric.api()
+	v := make(url.Values)
+	v.Set("begin", i.Begin.In(statLocation).Format(statTimeFormat))
+	v.Set("end", i.End.In(statLocation).Format(statTimeFormat))
+	v.Set("g", string(i.granularity()))
+	if sel == "" {
+		if i.Bucket != "" {
+			v.Set("bucket", i.Bucket)
+		}
+		if i.Region != "" {
+			v.Set("region", i.Region)
+		}
+		return path + "?" + v.Encode()
+	}
+
+	// rs_put和blob_io的过滤条件以$开头
+	v.Set("select", sel)
+	switch i.Metric {
+	case MetricGetRequests:
+		v.Set("$metric", "hits")
+	case MetricFlowOut:
+		v.Set("$metric", "flow_out")
+	}
+	if i.Bucket != "" {
+		v.Set("$bucket", i.Bucket)
+	}
+	if i.Region != "" {
+		v.Set("$region", i.Region)
+	}
+	return path + "?" + v.Encode()
+}
+
+// GetStatisticsOutput 获取统计数据的结果
+type GetStatisticsOutput struct {
+	Metric      StatMetric
+	Granularity StatGranularity
+
+	// 按时间排序的统计数据
+	Points []StatPoint
+}
+
+// Sum 返回所有统计值的和， 适用于请求次数和流量
+func (o *GetStatisticsOutput) Sum() int64 {
+	var sum int64
+	for _, p := range o.Points {
+		sum += p.Value
+	}
+	return sum
+}
+
+// Max 返回最大的统计值， 适用于存储量和文件数量
+func (o *GetStatisticsOutput) Max() int64 {
+	var max int64
+	for _, p := range o.Points {
+		if p.Value > max {
+			max = p.Value
+		}
+	}
+	return max
+}
+
+// Last 返回最后一个统计值， 没有数据的时候返回0
+func (o *GetStatisticsOutput) Last() int64 {
+	if len(o.Points) == 0 {
+		return 0
+	}
+	return o.Points[len(o.Points)-1].Value
+}
+
+// statSeries /v6/space和/v6/count返回的数据格式
+type statSeries struct {
+	Times []int64 `json:"times"`
+	Datas []int64 `json:"datas"`
+}
+
+// statValues /v6/rs_put和/v6/blob_io返回的数据格式
+type statValues struct {
+	Time   string           `json:"time"`
+	Values map[string]int64 `json:"values"`
+}
+
+// decode 把接口返回的数据转换成按时间排序的统计数据
+func (o *GetStatisticsOutput) decode(sel string, data json.RawMessage) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if sel == "" {
+		var s statSeries
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		if len(s.Times) != len(s.Datas) {
+			return fmt.Errorf("times and datas length mismatch, %d != %d", len(s.Times), len(s.Datas))
+		}
+		for n, t := range s.Times {
+			o.Points = append(o.Points, StatPoint{Time: time.Unix(t, 0), Value: s.Datas[n]})
+		}
+	} else {
+		var vs []statValues
+		if err := json.Unmarshal(data, &vs); err != nil {
+			return err
+		}
+		for _, v := range vs {
+			t, err := time.Parse(time.RFC3339, v.Time)
+			if err != nil {
+				return err
+			}
+			o.Points = append(o.Points, StatPoint{Time: t, Value: v.Values[sel]})
+		}
+	}
+	sort.SliceStable(o.Points, func(i, j int) bool {
+		return o.Points[i].Time.Before(o.Points[j].Time)
+	})
+	return nil
+}
+
+// GetStatisticsRequest 生成一个获取统计数据的请求
+func (m *StatisticsManager) GetStatisticsRequest(input *GetStatisticsInput) (req *request.Request, output *GetStatisticsOutput) {
+	if input == nil {
+		input = &GetStatisticsInput{}
+	}
+	output = &GetStatisticsOutput{Metric: input.Metric, Granularity: input.granularity()}
+
+	host := qiniu.StringValue(m.Config.APIHost)
+	if host == "" {
+		host = defs.DefaultAPIHost
+	}
+	op := &request.API{
+		Method:      "GET",
+		Host:        host,
+		TokenType:   credentials.TokenQBox,
+		ServiceName: ServiceName,
+		APIName:     "GetStatistics",
+	}
+	err := input.Validate()
+	if err == nil {
+		op.Path = input.path()
+	}
+
+	var raw json.RawMessage
+	req = m.newRequest(op, nil, &raw)
+	_, sel, _ := input.Metric.api()
+	req.Handlers.Unmarshal.PushBack(func(r *request.Request) {
+		if r.Error != nil {
+			return
+		}
+		if err := output.decode(sel, raw); err != nil {
+			r.Error = qerr.New(qerr.ErrCodeDeserialization, "failed to decode statistics", err)
+		}
+	})
+	if err != nil {
+		req.Error = err
+	}
+	return
+}
+
+// GetStatistics 获取一个统计项的时间序列
+func (m *StatisticsManager) GetStatistics(input *GetStatisticsInput) (*GetStatisticsOutput, error) {
+	req, out := m.GetStatisticsRequest(input)
+	return out, req.Send()
+}
+
+// GetStatisticsWithContext 和GetStatistics一样， 可以使用ctx取消请求
+func (m *StatisticsManager) GetStatisticsWithContext(ctx context.Context, input *GetStatisticsInput, opts ...request.Option) (*GetStatisticsOutput, error) {
+	req, out := m.GetStatisticsRequest(input)
+	return out, sendWithContext(ctx, req, opts...)
+}
+
+// AggregateStatisticsInput 跨区域汇总统计数据的输入参数
+type AggregateStatisticsInput struct {
+	// 统计项， 存储空间， 时间范围和粒度， Region会被忽略
+	GetStatisticsInput
+
+	// 要汇总的区域， 为空的时候使用defs.Regions()
+	Regions []string
+}
+
+// AggregateStatisticsOutput 跨区域汇总统计数据的结果
+type AggregateStatisticsOutput struct {
+	// 所有区域按时间相加的统计数据
+	GetStatisticsOutput
+
+	// 每个区域的统计数据
+	Regions map[string]*GetStatisticsOutput
+}
+
+// AggregateStatistics 并发获取每个区域的统计数据， 按时间相加得到汇总的统计数据
+// 任何一个区域失败的时候返回第一个错误
+func (m *StatisticsManager) AggregateStatistics(ctx context.Context, input *AggregateStatisticsInput, opts ...request.Option) (*AggregateStatisticsOutput, error) {
+	if input == nil {
+		input = &AggregateStatisticsInput{}
+	}
+	if err := input.GetStatisticsInput.Validate(); err != nil {
+		return nil, err
+	}
+	regions := input.Regions
+	if len(regions) == 0 {
+		regions = defs.Regions()
+	}
+
+	outputs := make([]*GetStatisticsOutput, len(regions))
+	errs := make([]error, len(regions))
+	var wg sync.WaitGroup
+	for n, region := range regions {
+		wg.Add(1)
+		go func(n int, region string) {
+			defer wg.Done()
+			in := input.GetStatisticsInput
+			in.Region = region
+			outputs[n], errs[n] = m.GetStatisticsWithContext(ctx, &in, opts...)
+		}(n, region)
+	}
+	wg.Wait()
+
+	output := &AggregateStatisticsOutput{
+		GetStatisticsOutput: GetStatisticsOutput{Metric: input.Metric, Granularity: input.granularity()},
+		Regions:             make(map[string]*GetStatisticsOutput, len(regions)),
+	}
+	sums := make(map[int64]int64)
+	for n, region := range regions {
+		if errs[n] != nil {
+			return nil, errs[n]
+		}
+		output.Regions[region] = outputs[n]
+		for _, p := range outputs[n].Points {
+			sums[p.Time.Unix()] += p.Value
+		}
+	}
+	for t, v := range sums {
+		output.Points = append(output.Points, StatPoint{Time: time.Unix(t, 0), Value: v})
+	}
+	sort.Slice(output.Points, func(i, j int) bool {
+		return output.Points[i].Time.Before(output.Points[j].Time)
+	})
+	return output, nil
+}
@@ -0,0 +1,379 @@
+package kodo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/defs"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qetag"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+const (
+	// ErrTooManyParts 数据流太大， 按照当前的分片大小分片数量超过了MaxUploadParts
+	ErrTooManyParts = "TooManyPartsError"
+)
+
+// UploadStreamInput 上传数据流的输入参数
+type UploadStreamInput struct {
+	// 上传凭证， 如果为空， 会使用PutPolicy和Config.Credentials生成上传凭证
+	UpToken string
+
+	// 上传策略， 只有在UpToken为空的时候使用
+	PutPolicy *PutPolicy
+
+	// 资源名， 为nil的时候由服务端根据上传策略中的saveKey或者文件hash生成
+	Key *string
+
+	// 要上传的数据流， 不需要可Seek, 也不需要知道数据的总大小
+	Body io.Reader
+
+	// 原始的文件名， 用于魔法变量$(fname)
+	FileName string
+
+	// 文件的MimeType, 为空的时候由服务端自动判断
+	MimeType string
+
+	// 自定义变量， key必须以"x:"开头
+	CustomVars map[string]string
+
+	// 自定义的元数据， key必须以"x-qn-meta-"开头
+	Metadata map[string]string
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+}
+
+// Validate 检查输入的参数
+func (i *UploadStreamInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "UploadStreamInput"}
+	if i.UpToken == "" && i.PutPolicy == nil {
+		invalidParams.Add(request.NewErrParamRequired("UpToken"))
+	}
+	if i.Body == nil {
+		invalidParams.Add(request.NewErrParamRequired("Body"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// UploadStreamOutput 上传数据流的返回结果
+type UploadStreamOutput struct {
+	PutRet
+
+	// 从数据流中读取并上传的数据大小
+	Size int64
+
+	// 上传过程中计算的数据的qetag, 和存储空间中资源的hash一致
+	Etag string
+}
+
+// UploadStream 上传不可Seek、大小未知的数据流， 例如管道、数据库导出或者tar流
+//
+// 数据按照PartSize切分， 每个分片先读取到缓冲区中再上传， 这样分片上传失败的时候可以重试
+// 缓冲区的数量最多为Concurrency+1, 所以占用的内存不超过PartSize*(Concurrency+1)
+// 数据流不超过defs.DefaultFormSize的时候使用表单上传
+// 数据流无法重新读取， 所以不支持断点续传， 上传失败的时候会放弃已经上传的分片
+func (u *Uploader) UploadStream(input *UploadStreamInput) (*UploadStreamOutput, error) {
+	return u.UploadStreamWithContext(context.Background(), input)
+}
+
+// UploadStreamWithContext 和UploadStream一样， 可以使用ctx取消上传
+func (u *Uploader) UploadStreamWithContext(ctx context.Context, input *UploadStreamInput) (*UploadStreamOutput, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	token, err := u.svc.uploadToken(input.UpToken, input.PutPolicy)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := DecodeUploadToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &streamUploader{
+		u:     u,
+		input: input,
+		upload: MultipartUpload{
+			UpToken: token,
+			Bucket:  policy.Bucket(),
+			Key:     input.Key,
+			Region:  input.Region,
+		},
+		partSize: u.streamPartSize(),
+		etag:     qetag.New(),
+	}
+	ret, err := s.run(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &UploadStreamOutput{PutRet: *ret, Size: s.size, Etag: s.etag.Etag()}
+	if ret.Hash == "" {
+		out.Hash = out.Etag
+	} else if u.VerifyEtag {
+		if err := qetag.Verify(ret.Hash, out.Etag); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// streamPartSize 返回上传数据流使用的分片大小
+// 数据的总大小未知， 不能根据MaxUploadParts自动调整， 只限制在MinPartSize和MaxPartSize之间
+func (u *Uploader) streamPartSize() int64 {
+	partSize := u.PartSize
+	if partSize < MinPartSize {
+		partSize = MinPartSize
+	}
+	if partSize > MaxPartSize {
+		partSize = MaxPartSize
+	}
+	return partSize
+}
+
+// partBufferPool 有界的分片缓冲区池， 没有空闲的缓冲区的时候阻塞读取数据流
+type partBufferPool struct {
+	bufs chan []byte
+	size int64
+}
+
+func newPartBufferPool(n int, size int64) *partBufferPool {
+	return &partBufferPool{bufs: make(chan []byte, n), size: size}
+}
+
+// get 返回一个空闲的缓冲区， 缓冲区按需分配， 数量不超过池的容量
+func (p *partBufferPool) get(ctx context.Context, allocated *int) ([]byte, error) {
+	if *allocated < cap(p.bufs) {
+		select {
+		case buf := <-p.bufs:
+			return buf, nil
+		default:
+		}
+		*allocated++
+		return make([]byte, p.size), nil
+	}
+	select {
+	case buf := <-p.bufs:
+		return buf, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *partBufferPool) put(buf []byte) {
+	p.bufs <- buf[:cap(buf)]
+}
+
+// streamPart 已经读取到缓冲区中等待上传的分片
+type streamPart struct {
+	number int
+	buf    []byte
+	data   []byte
+}
+
+// streamUploader 保存一次数据流上传的状态
+type streamUploader struct {
+	u        *Uploader
+	input    *UploadStreamInput
+	upload   MultipartUpload
+	partSize int64
+
+	// 从数据流中读取的数据大小和qetag, 只在读取数据的goroutine中修改
+	size int64
+	etag *qetag.Hasher
+
+	mu       sync.Mutex
+	parts    []CompletedPart
+	uploaded int64
+	err      error
+}
+
+func (s *streamUploader) logf(format string, args ...interface{}) {
+	cfg := s.u.svc.Config
+	if !cfg.LogLevel.Matches(qiniu.LogDebugMultipartUpload) || cfg.Logger == nil {
+		return
+	}
+	cfg.Logger.Log(fmt.Sprintf("DEBUG: stream upload %s: ", s.upload.Bucket) + fmt.Sprintf(format, args...))
+}
+
+// read 从数据流中读取一个分片大小的数据， 同时计算qetag
+// 数据流结束的时候返回io.EOF, 这时候data可能不为空
+func (s *streamUploader) read(buf []byte) ([]byte, error) {
+	n, err := io.ReadFull(s.input.Body, buf)
+	data := buf[:n]
+	s.etag.Write(data)
+	s.size += int64(n)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	if err != nil && err != io.EOF {
+		return data, qerr.New(request.ErrCodeRead, "failed to read upload stream", err)
+	}
+	return data, err
+}
+
+func (s *streamUploader) run(ctx context.Context) (*PutRet, error) {
+	concurrency := s.u.concurrency()
+	pool := newPartBufferPool(concurrency+1, s.partSize)
+	allocated := 0
+
+	// 先读取第一个分片， 数据流比较小的时候直接使用表单上传
+	buf, _ := pool.get(ctx, &allocated)
+	data, err := s.read(buf)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if err == io.EOF && s.size <= defs.DefaultFormSize {
+		return s.formUpload(ctx, data)
+	}
+	eof := err == io.EOF
+
+	out, err := s.u.svc.InitPartsWithContext(ctx, &InitPartsInput{MultipartUpload: s.upload})
+	if err != nil {
+		return nil, err
+	}
+	s.upload.UploadID = out.UploadID
+	s.logf("init upload %s", out.UploadID)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	parts := make(chan streamPart)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range parts {
+				if err := s.uploadPart(ctx, p); err != nil {
+					s.setErr(err)
+					cancel()
+				}
+				pool.put(p.buf)
+			}
+		}()
+	}
+
+	for number := 1; ; number++ {
+		if len(data) > 0 {
+			if number > MaxUploadParts {
+				s.setErr(qerr.New(ErrTooManyParts, fmt.Sprintf("upload stream exceeds %d parts of %s, increase PartSize",
+					MaxUploadParts, defs.Size(s.partSize)), nil))
+				break
+			}
+			select {
+			case parts <- streamPart{number: number, buf: buf, data: data}:
+			case <-ctx.Done():
+			}
+		}
+		if eof || ctx.Err() != nil {
+			break
+		}
+		if buf, err = pool.get(ctx, &allocated); err != nil {
+			break
+		}
+		data, err = s.read(buf)
+		if err == io.EOF {
+			eof = true
+		} else if err != nil {
+			s.setErr(err)
+			break
+		}
+	}
+	close(parts)
+	wg.Wait()
+
+	if s.err == nil && ctx.Err() != nil {
+		s.err = qerr.New(request.ErrCodeCanceled, "upload stream canceled", ctx.Err())
+	}
+	if s.err != nil {
+		s.abort()
+		return nil, s.err
+	}
+
+	sort.Slice(s.parts, func(i, j int) bool {
+		return s.parts[i].PartNumber < s.parts[j].PartNumber
+	})
+	ret, err := s.u.svc.CompletePartsWithContext(ctx, &CompletePartsInput{
+		MultipartUpload: s.upload,
+		Parts:           s.parts,
+		FileName:        s.input.FileName,
+		MimeType:        s.input.MimeType,
+		Metadata:        s.input.Metadata,
+		CustomVars:      s.input.CustomVars,
+	})
+	if err != nil {
+		s.abort()
+		return nil, err
+	}
+	s.logf("complete upload %s, %d parts, %s", s.upload.UploadID, len(s.parts), defs.Size(s.size))
+	return ret, nil
+}
+
+// formUpload 使用表单上传已经全部读取到内存中的数据流
+func (s *streamUploader) formUpload(ctx context.Context, data []byte) (*PutRet, error) {
+	out, err := s.u.svc.FormUploadWithContext(ctx, &FormUploadInput{
+		UpToken:    s.upload.UpToken,
+		Key:        s.input.Key,
+		Body:       bytes.NewReader(data),
+		FileName:   s.input.FileName,
+		MimeType:   s.input.MimeType,
+		CustomVars: s.input.CustomVars,
+		Metadata:   s.input.Metadata,
+		Region:     s.input.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out.PutRet, nil
+}
+
+func (s *streamUploader) uploadPart(ctx context.Context, p streamPart) error {
+	out, err := s.u.svc.UploadPartWithContext(ctx, &UploadPartInput{
+		MultipartUpload: s.upload,
+		PartNumber:      p.number,
+		Body:            bytes.NewReader(p.data),
+		ContentMD5:      partMD5(p.data),
+	})
+	if err != nil {
+		s.logf("upload part %d failed: %v", p.number, err)
+		return err
+	}
+	s.logf("upload part %d done, etag: %s", p.number, out.Etag)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.parts = append(s.parts, CompletedPart{PartNumber: p.number, Etag: out.Etag})
+	s.uploaded += int64(len(p.data))
+	if s.u.Recorder != nil {
+		// 数据的总大小未知
+		s.u.Recorder.Progress(s.upload.Bucket, qiniu.StringValue(s.upload.Key), s.uploaded, 0)
+	}
+	return nil
+}
+
+func (s *streamUploader) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// abort 放弃已经上传的分片， 数据流不能重新读取， 保留这些分片也无法续传
+func (s *streamUploader) abort() {
+	err := s.u.svc.AbortPartsWithContext(context.Background(), &AbortPartsInput{MultipartUpload: s.upload})
+	if err != nil {
+		s.logf("abort upload %s failed: %v", s.upload.UploadID, err)
+	}
+}
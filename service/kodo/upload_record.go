@@ -0,0 +1,202 @@
+package kodo
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/defaults"
+)
+
+// UploadRecord 分片上传的记录信息， 用于断点续传
+type UploadRecord struct {
+	// 分片上传的上传ID
+	UploadID string `json:"uploadId"`
+
+	// 上传ID的过期时间， Unix时间戳
+	ExpireAt int64 `json:"expireAt"`
+
+	// 分片的大小
+	PartSize int64 `json:"partSize"`
+
+	// 文件的大小
+	FileSize int64 `json:"fileSize"`
+
+	// 文件的修改时间， 单位为纳秒
+	ModTime int64 `json:"modTime"`
+
+	// 已经上传成功的分片
+	Parts []RecordPart `json:"parts"`
+}
+
+// RecordPart 已经上传成功的分片信息
+type RecordPart struct {
+	PartNumber int    `json:"partNumber"`
+	Etag       string `json:"etag"`
+	Size       int64  `json:"size"`
+}
+
+// Expired 返回上传ID是否已经过期， 留出一个小时的余量防止上传过程中过期
+func (r *UploadRecord) Expired() bool {
+	return time.Now().Add(time.Hour).Unix() >= r.ExpireAt
+}
+
+// Match 判断上传记录是否可以用于续传大小为size, 修改时间为modTime的文件
+// 文件在上次上传后被修改过， 或者分片大小不一样的时候返回false
+func (r *UploadRecord) Match(size, modTime, partSize int64) bool {
+	return !r.Expired() && r.FileSize == size && r.ModTime == modTime && r.PartSize == partSize
+}
+
+// RecordStore 保存分片上传记录的接口， 用于断点续传
+// 上传记录的名字由RecordName生成， 实现需要保证多个goroutine并发调用是安全的
+type RecordStore interface {
+	// Load 读取上传记录， 如果记录不存在， 返回nil, nil
+	Load(name string) (*UploadRecord, error)
+
+	// Save 保存上传记录， 如果记录已经存在， 覆盖原有的记录
+	Save(name string, r *UploadRecord) error
+
+	// Delete 删除上传记录， 记录不存在的时候不返回错误
+	Delete(name string) error
+}
+
+// RecordName 根据存储空间， 资源名， 文件指纹生成上传记录的名字
+// 文件指纹用来区分上传到同一个资源的不同的文件， 一般使用文件的绝对路径
+func RecordName(bucket string, key *string, fingerprint string) string {
+	h := sha1.New()
+	h.Write([]byte(bucket))
+	h.Write([]byte{0})
+	if key != nil {
+		h.Write([]byte(*key))
+	}
+	h.Write([]byte{0})
+	h.Write([]byte(fingerprint))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// FileRecordStore 把上传记录保存在本地目录中， 每个记录一个文件
+// 目录可以是共享的存储卷， 这样容器重启后依然可以续传
+type FileRecordStore struct {
+	// 保存上传记录的目录
+	Dir string
+}
+
+// NewFileRecordStore 返回一个FileRecordStore指针， dir为空的时候使用$HOME/.qiniu/records
+func NewFileRecordStore(dir string) *FileRecordStore {
+	if dir == "" {
+		dir = filepath.Join(defaults.UserHomeDir(), ".qiniu", "records")
+	}
+	return &FileRecordStore{Dir: dir}
+}
+
+// Load 实现了RecordStore接口， 已经过期的记录会被删除
+func (s *FileRecordStore) Load(name string) (*UploadRecord, error) {
+	data, err := ioutil.ReadFile(filepath.Join(s.Dir, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r UploadRecord
+	if err := json.Unmarshal(data, &r); err != nil {
+		// 记录文件损坏， 直接丢弃
+		return nil, s.Delete(name)
+	}
+	if r.Expired() {
+		return nil, s.Delete(name)
+	}
+	return &r, nil
+}
+
+// Save 实现了RecordStore接口
+// 先写入临时文件然后重命名， 防止进程崩溃的时候记录文件只写了一半
+func (s *FileRecordStore) Save(name string, r *UploadRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(s.Dir, name+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.Dir, name))
+}
+
+// Delete 实现了RecordStore接口
+func (s *FileRecordStore) Delete(name string) error {
+	err := os.Remove(filepath.Join(s.Dir, name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// MemoryRecordStore 把上传记录保存在内存中， 只能在进程内续传
+// 主要用于同一个进程内重试上传或者测试
+type MemoryRecordStore struct {
+	mu      sync.Mutex
+	records map[string]UploadRecord
+}
+
+// NewMemoryRecordStore 返回一个MemoryRecordStore指针
+func NewMemoryRecordStore() *MemoryRecordStore {
+	return &MemoryRecordStore{records: make(map[string]UploadRecord)}
+}
+
+// Load 实现了RecordStore接口， 已经过期的记录会被删除
+func (s *MemoryRecordStore) Load(name string) (*UploadRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[name]
+	if !ok {
+		return nil, nil
+	}
+	if r.Expired() {
+		delete(s.records, name)
+		return nil, nil
+	}
+	r.Parts = append([]RecordPart(nil), r.Parts...)
+	return &r, nil
+}
+
+// Save 实现了RecordStore接口
+func (s *MemoryRecordStore) Save(name string, r *UploadRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.records == nil {
+		s.records = make(map[string]UploadRecord)
+	}
+	c := *r
+	c.Parts = append([]RecordPart(nil), r.Parts...)
+	s.records[name] = c
+	return nil
+}
+
+// Delete 实现了RecordStore接口
+func (s *MemoryRecordStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, name)
+	return nil
+}
@@ -0,0 +1,543 @@
+package kodo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/QN-zhangzhuo/go-sdk/qiniu"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/defs"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qerr"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/qetag"
+	"github.com/QN-zhangzhuo/go-sdk/qiniu/request"
+)
+
+const (
+	// DefaultUploadConcurrency 分片上传默认的最大并发数
+	DefaultUploadConcurrency = 4
+
+	// DefaultStoreNumber 默认每上传成功多少个分片保存一次上传记录
+	DefaultStoreNumber = 5
+
+	// DefaultPartSize 分片上传默认的分片大小
+	DefaultPartSize = 4 * defs.MB
+)
+
+// ProgressRecorder 分片上传和分段下载的进度接口， 每当上传或者下载成功一个分片， 就会调用Progress方法
+type ProgressRecorder interface {
+	// uploaded 已经上传或者下载成功的数据大小， total 数据的总大小
+	Progress(bucket, key string, uploaded, total int64)
+}
+
+// ProgressRecorderFunc 用来封装函数， 方便地实现ProgressRecorder接口
+type ProgressRecorderFunc func(bucket, key string, uploaded, total int64)
+
+// Progress 用参数调用封装的函数
+func (f ProgressRecorderFunc) Progress(bucket, key string, uploaded, total int64) {
+	f(bucket, key, uploaded, total)
+}
+
+// logProgressRecorder 使用Logger输出进度， 是分片上传默认的进度实现
+type logProgressRecorder struct {
+	logger qiniu.Logger
+
+	// 输出的操作名， "upload"或者"download"
+	op string
+}
+
+func (l logProgressRecorder) Progress(bucket, key string, uploaded, total int64) {
+	if l.logger == nil {
+		return
+	}
+	if total > 0 {
+		l.logger.Log(fmt.Sprintf("kodo: %s %s/%s %s/%s (%.2f%%)", l.op, bucket, key,
+			defs.Size(uploaded), defs.Size(total), float64(uploaded)*100/float64(total)))
+	} else {
+		l.logger.Log(fmt.Sprintf("kodo: %s %s/%s %s", l.op, bucket, key, defs.Size(uploaded)))
+	}
+}
+
+// Uploader 分片上传的管理器， 可以并发上传分片， 支持断点续传
+// Uploader的字段默认从Kodo客户端的Config中获取， 创建后可以修改
+//
+//	uploader := kodo.NewUploader(svc, func(u *kodo.Uploader) {
+//		u.PartSize = 8 * defs.MB
+//	})
+//	ret, err := uploader.UploadFile(&kodo.UploadFileInput{...})
+type Uploader struct {
+	// 分片的大小， 如果文件太大导致分片数量超过MaxUploadParts, 会自动调大分片的大小
+	// 每个并发上传的分片会被读取到内存中计算MD5, 占用的内存大约为PartSize*Concurrency
+	PartSize int64
+
+	// 最大的并发上传数量， 对应Config.UploadConcurrency, 小于等于0的时候使用DefaultUploadConcurrency
+	Concurrency int
+
+	// 每上传成功StoreNumber个分片保存一次上传记录， 对应Config.StoreNumber
+	StoreNumber int
+
+	// 是否禁用断点续传， 对应Config.DisableResume
+	DisableResume bool
+
+	// 上传进度的实现， 为nil的时候不输出上传进度
+	// 对应Config.ProgressRecorder和Config.DisableRecorder
+	Recorder ProgressRecorder
+
+	// 保存上传记录的实现， 默认保存在$HOME/.qiniu/records目录下
+	RecordStore RecordStore
+
+	// 为true的时候， 上传之前计算文件的qetag, 存储空间中已经有hash相同的同名文件就跳过上传
+	// 只对指定了Key的上传有效， 查询文件的hash需要Config.Credentials
+	SkipUnchanged bool
+
+	// 为true的时候， 上传完成之后比较服务端返回的hash和本地计算的qetag, 不一致的时候返回qerr.ErrEtagMismatch
+	// 上传凭证的returnBody中没有hash的时候不比较
+	VerifyEtag bool
+
+	svc *Kodo
+}
+
+// concurrency 返回实际使用的并发数， 至少有一个goroutine上传分片
+func (u *Uploader) concurrency() int {
+	if u.Concurrency <= 0 {
+		return DefaultUploadConcurrency
+	}
+	return u.Concurrency
+}
+
+// NewUploader 返回一个Uploader指针， options可以用来修改Uploader的默认配置
+func NewUploader(svc *Kodo, options ...func(*Uploader)) *Uploader {
+	cfg := svc.Config
+	u := &Uploader{
+		PartSize:      DefaultPartSize,
+		Concurrency:   DefaultUploadConcurrency,
+		StoreNumber:   DefaultStoreNumber,
+		DisableResume: qiniu.BoolValue(cfg.DisableResume),
+		RecordStore:   NewFileRecordStore(""),
+		svc:           svc,
+	}
+	if n := qiniu.IntValue(cfg.UploadConcurrency); n > 0 {
+		u.Concurrency = n
+	}
+	if n := qiniu.IntValue(cfg.StoreNumber); n > 0 {
+		u.StoreNumber = n
+	}
+	if !qiniu.BoolValue(cfg.DisableRecorder) {
+		if r, ok := cfg.ProgressRecorder.(ProgressRecorder); ok {
+			u.Recorder = r
+		} else {
+			u.Recorder = logProgressRecorder{logger: cfg.Logger, op: "upload"}
+		}
+	}
+
+	for _, option := range options {
+		option(u)
+	}
+	return u
+}
+
+// UploadFileInput 上传本地文件的输入参数
+type UploadFileInput struct {
+	// 上传凭证， 如果为空， 会使用PutPolicy和Config.Credentials生成上传凭证
+	UpToken string
+
+	// 上传策略， 只有在UpToken为空的时候使用
+	PutPolicy *PutPolicy
+
+	// 资源名， 为nil的时候由服务端根据上传策略中的saveKey或者文件hash生成
+	Key *string
+
+	// 本地文件的路径
+	FilePath string
+
+	// 原始的文件名， 为空的时候使用FilePath的文件名
+	FileName string
+
+	// 文件的MimeType, 为空的时候由服务端自动判断
+	MimeType string
+
+	// 自定义变量， key必须以"x:"开头
+	CustomVars map[string]string
+
+	// 自定义的元数据， key必须以"x-qn-meta-"开头
+	Metadata map[string]string
+
+	// 存储空间所在的区域， 为空的时候使用Config.Region
+	Region string
+}
+
+// Validate 检查输入的参数
+func (i *UploadFileInput) Validate() error {
+	invalidParams := request.ErrInvalidParams{Context: "UploadFileInput"}
+	if i.UpToken == "" && i.PutPolicy == nil {
+		invalidParams.Add(request.NewErrParamRequired("UpToken"))
+	}
+	if i.FilePath == "" {
+		invalidParams.Add(request.NewErrParamRequired("FilePath"))
+	}
+	if invalidParams.Len() > 0 {
+		return invalidParams
+	}
+	return nil
+}
+
+// UploadFile 上传本地文件
+// 文件大小不超过defs.DefaultFormSize的时候使用表单上传， 否则使用分片上传
+func (u *Uploader) UploadFile(input *UploadFileInput) (*PutRet, error) {
+	return u.UploadFileWithContext(context.Background(), input)
+}
+
+// UploadFileWithContext 和UploadFile一样， 可以使用ctx取消上传
+func (u *Uploader) UploadFileWithContext(ctx context.Context, input *UploadFileInput) (*PutRet, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+	token, err := u.svc.uploadToken(input.UpToken, input.PutPolicy)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := DecodeUploadToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(input.FilePath)
+	if err != nil {
+		return nil, qerr.New(qerr.ErrOpenFile, "failed to open file: "+input.FilePath, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, qerr.New(qerr.ErrOpenFile, "failed to stat file: "+input.FilePath, err)
+	}
+
+	var etag string
+	if u.SkipUnchanged || u.VerifyEtag {
+		if etag, err = qetag.Reader(f); err == nil {
+			_, err = f.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			return nil, qerr.New(qerr.ErrOpenFile, "failed to read file: "+input.FilePath, err)
+		}
+	}
+	if u.SkipUnchanged && input.Key != nil {
+		stat, err := NewBucketManager(u.svc).StatWithContext(ctx, &StatInput{
+			Entry:  Entry{Bucket: policy.Bucket(), Key: *input.Key},
+			Region: input.Region,
+		})
+		if err == nil && stat.Hash == etag {
+			return &PutRet{Key: *input.Key, Hash: etag}, nil
+		}
+		if err != nil && !isNotExistErr(err) {
+			return nil, err
+		}
+	}
+
+	ret, err := u.uploadFile(ctx, input, token, policy, f, info)
+	if err != nil {
+		return nil, err
+	}
+	if u.VerifyEtag && ret.Hash != "" {
+		if err := qetag.Verify(ret.Hash, etag); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// uploadFile 根据文件的大小选择表单上传或者分片上传
+func (u *Uploader) uploadFile(ctx context.Context, input *UploadFileInput, token string, policy *PutPolicy,
+	f *os.File, info os.FileInfo) (*PutRet, error) {
+	fileName := input.FileName
+	if fileName == "" {
+		fileName = filepath.Base(input.FilePath)
+	}
+
+	if info.Size() <= defs.DefaultFormSize {
+		out, err := u.svc.FormUploadWithContext(ctx, &FormUploadInput{
+			UpToken:    token,
+			Key:        input.Key,
+			Body:       f,
+			FileName:   fileName,
+			MimeType:   input.MimeType,
+			CustomVars: input.CustomVars,
+			Metadata:   input.Metadata,
+			Region:     input.Region,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &out.PutRet, nil
+	}
+
+	absPath, err := filepath.Abs(input.FilePath)
+	if err != nil {
+		absPath = input.FilePath
+	}
+	m := &multipartUploader{
+		u: u,
+		upload: MultipartUpload{
+			UpToken: token,
+			Bucket:  policy.Bucket(),
+			Key:     input.Key,
+			Region:  input.Region,
+		},
+		input:      input,
+		fileName:   fileName,
+		file:       f,
+		size:       info.Size(),
+		modTime:    info.ModTime().UnixNano(),
+		recordName: RecordName(policy.Bucket(), input.Key, absPath),
+	}
+	return m.run(ctx)
+}
+
+// multipartUploader 保存一次分片上传的状态
+type multipartUploader struct {
+	u      *Uploader
+	upload MultipartUpload
+	input  *UploadFileInput
+
+	fileName string
+	file     *os.File
+	size     int64
+	modTime  int64
+
+	recordName string
+	record     *UploadRecord
+
+	mu        sync.Mutex
+	uploaded  int64
+	sinceSave int
+	err       error
+}
+
+func (m *multipartUploader) logf(format string, args ...interface{}) {
+	cfg := m.u.svc.Config
+	if !cfg.LogLevel.Matches(qiniu.LogDebugMultipartUpload) || cfg.Logger == nil {
+		return
+	}
+	cfg.Logger.Log(fmt.Sprintf("DEBUG: multipart upload %s: ", m.upload.Bucket) + fmt.Sprintf(format, args...))
+}
+
+// partSize 返回实际使用的分片大小， 保证分片数量不超过MaxUploadParts
+func (m *multipartUploader) partSize() int64 {
+	partSize := m.u.PartSize
+	if partSize < MinPartSize {
+		partSize = MinPartSize
+	}
+	if partSize > MaxPartSize {
+		partSize = MaxPartSize
+	}
+	if (m.size+partSize-1)/partSize > MaxUploadParts {
+		partSize = (m.size + MaxUploadParts - 1) / MaxUploadParts
+		partSize = (partSize + defs.MB - 1) / defs.MB * defs.MB
+	}
+	return partSize
+}
+
+// init 加载上传记录， 如果没有可用的上传记录， 初始化一个新的分片上传
+func (m *multipartUploader) init(ctx context.Context, partSize int64) error {
+	if m.resumable() {
+		r, err := m.u.RecordStore.Load(m.recordName)
+		if err != nil {
+			m.logf("load upload record failed: %v", err)
+		}
+		if r != nil && r.Match(m.size, m.modTime, partSize) {
+			m.logf("resume upload %s, %d parts uploaded", r.UploadID, len(r.Parts))
+			m.record = r
+			return nil
+		}
+		if r != nil {
+			m.logf("discard stale upload record %s", r.UploadID)
+			if err := m.u.RecordStore.Delete(m.recordName); err != nil {
+				m.logf("delete upload record failed: %v", err)
+			}
+		}
+	}
+
+	out, err := m.u.svc.InitPartsWithContext(ctx, &InitPartsInput{MultipartUpload: m.upload})
+	if err != nil {
+		return err
+	}
+	m.logf("init upload %s", out.UploadID)
+	m.record = &UploadRecord{
+		UploadID: out.UploadID,
+		ExpireAt: out.ExpireAt,
+		PartSize: partSize,
+		FileSize: m.size,
+		ModTime:  m.modTime,
+	}
+	return nil
+}
+
+func (m *multipartUploader) run(ctx context.Context) (*PutRet, error) {
+	partSize := m.partSize()
+	if err := m.init(ctx, partSize); err != nil {
+		return nil, err
+	}
+	m.upload.UploadID = m.record.UploadID
+
+	done := make(map[int]bool, len(m.record.Parts))
+	for _, p := range m.record.Parts {
+		done[p.PartNumber] = true
+		m.uploaded += p.Size
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	partCount := int((m.size + partSize - 1) / partSize)
+	concurrency := m.u.concurrency()
+	parts := make(chan int, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var buf []byte
+			for n := range parts {
+				if err := m.uploadPart(ctx, n, partSize, &buf); err != nil {
+					m.setErr(err)
+					cancel()
+				}
+			}
+		}()
+	}
+	for n := 1; n <= partCount; n++ {
+		if done[n] {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		parts <- n
+	}
+	close(parts)
+	wg.Wait()
+
+	if m.err != nil {
+		m.finishRecord(m.err)
+		return nil, m.err
+	}
+
+	sort.Slice(m.record.Parts, func(i, j int) bool {
+		return m.record.Parts[i].PartNumber < m.record.Parts[j].PartNumber
+	})
+	completed := make([]CompletedPart, 0, len(m.record.Parts))
+	for _, p := range m.record.Parts {
+		completed = append(completed, CompletedPart{PartNumber: p.PartNumber, Etag: p.Etag})
+	}
+	ret, err := m.u.svc.CompletePartsWithContext(ctx, &CompletePartsInput{
+		MultipartUpload: m.upload,
+		Parts:           completed,
+		FileName:        m.fileName,
+		MimeType:        m.input.MimeType,
+		Metadata:        m.input.Metadata,
+		CustomVars:      m.input.CustomVars,
+	})
+	m.finishRecord(err)
+	if err != nil {
+		return nil, err
+	}
+	m.logf("complete upload %s, %d parts", m.upload.UploadID, len(completed))
+	return ret, nil
+}
+
+// uploadPart 上传一个分片， 分片数据只从文件中读取一次到buf中， 计算MD5之后从buf发送， 请求重试的时候不需要重新读取文件
+func (m *multipartUploader) uploadPart(ctx context.Context, partNumber int, partSize int64, buf *[]byte) error {
+	offset := int64(partNumber-1) * partSize
+	size := partSize
+	if offset+size > m.size {
+		size = m.size - offset
+	}
+	if int64(cap(*buf)) < size {
+		*buf = make([]byte, size)
+	}
+	data := (*buf)[:size]
+	if n, err := m.file.ReadAt(data, offset); int64(n) < size {
+		return qerr.New(request.ErrCodeRead, fmt.Sprintf("failed to read part %d", partNumber), err)
+	}
+	out, err := m.u.svc.UploadPartWithContext(ctx, &UploadPartInput{
+		MultipartUpload: m.upload,
+		PartNumber:      partNumber,
+		Body:            bytes.NewReader(data),
+		ContentMD5:      partMD5(data),
+	})
+	if err != nil {
+		m.logf("upload part %d failed: %v", partNumber, err)
+		return err
+	}
+	m.logf("upload part %d done, etag: %s", partNumber, out.Etag)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.record.Parts = append(m.record.Parts, RecordPart{PartNumber: partNumber, Etag: out.Etag, Size: size})
+	m.uploaded += size
+	if m.u.Recorder != nil {
+		m.u.Recorder.Progress(m.upload.Bucket, qiniu.StringValue(m.upload.Key), m.uploaded, m.size)
+	}
+	m.sinceSave++
+	if m.resumable() && m.sinceSave >= m.u.StoreNumber {
+		m.sinceSave = 0
+		if err := m.u.RecordStore.Save(m.recordName, m.record); err != nil {
+			m.logf("save upload record failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// resumable 返回是否开启了断点续传
+func (m *multipartUploader) resumable() bool {
+	return !m.u.DisableResume && m.u.RecordStore != nil
+}
+
+func (m *multipartUploader) setErr(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err == nil {
+		m.err = err
+	}
+}
+
+// finishRecord 根据上传的结果处理上传记录
+// 上传成功或者上传ID已经失效的时候删除上传记录， 否则保存上传记录以便下次续传
+func (m *multipartUploader) finishRecord(err error) {
+	if !m.resumable() {
+		return
+	}
+	if err == nil || isInvalidUploadErr(err) {
+		if dErr := m.u.RecordStore.Delete(m.recordName); dErr != nil {
+			m.logf("delete upload record failed: %v", dErr)
+		}
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if sErr := m.u.RecordStore.Save(m.recordName, m.record); sErr != nil {
+		m.logf("save upload record failed: %v", sErr)
+	}
+}
+
+// isNotExistErr 判断是否是资源不存在的错误
+func isNotExistErr(err error) bool {
+	if aerr, ok := err.(qerr.Error); ok {
+		return aerr.Code() == qerr.ErrResourceNotExist
+	}
+	return false
+}
+
+// isInvalidUploadErr 判断是否是上传ID失效导致的错误
+func isInvalidUploadErr(err error) bool {
+	if aerr, ok := err.(qerr.Error); ok {
+		switch aerr.Code() {
+		case qerr.ErrInvalidCtx, qerr.ErrResourceNotExist:
+			return true
+		}
+	}
+	return false
+}